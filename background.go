@@ -0,0 +1,219 @@
+package text2img
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// Fit is the way a background image is fitted to the canvas
+type Fit string
+
+// Fit modes
+const (
+	// FitCover scales the image to cover the whole canvas, cropping it around the focal point
+	FitCover Fit = "cover"
+	// FitContain scales the image to fit in the canvas, the background color filling the rest
+	FitContain Fit = "contain"
+	// FitStretch scales the image to the size of the canvas, ignoring its aspect ratio
+	FitStretch Fit = "stretch"
+	// FitTile repeats the image at its own size
+	FitTile Fit = "tile"
+	// FitCenter draws the image at its own size, centered on the focal point when larger than the canvas
+	FitCenter Fit = "center"
+)
+
+// ImageOrder is the order the background images of a folder are assigned to snippets in
+type ImageOrder string
+
+// Image orders
+const (
+	// ImageRoundRobin assigns the images in the order of their file names, starting over after the last one
+	ImageRoundRobin ImageOrder = "round-robin"
+	// ImageRandom assigns random images, seeded like the colors so that the same notes get the same images
+	ImageRandom ImageOrder = "random"
+)
+
+// FocalPoint is the point of a background image kept in view when it is cropped,
+// as fractions of its width and height from its top left corner
+type FocalPoint struct {
+	X float64
+	Y float64
+}
+
+// fittedBackground returns the background image fitted to the canvas, transparent where the image doesn't cover it.
+// Images are scaled once and cached, as every snippet is drawn on the same background.
+func (d *drawer) fittedBackground(src image.Image) *image.RGBA {
+	key := fitKey{src, d.BackgroundFit, d.FocalPoint, d.Width, d.Height}
+	if img, ok := d.fitted[key]; ok {
+		return img
+	}
+
+	canvas := image.Rect(0, 0, d.Width, d.Height)
+	img := image.NewRGBA(canvas)
+	bounds := src.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+
+	switch d.BackgroundFit {
+	case FitStretch:
+		xdraw.CatmullRom.Scale(img, canvas, src, bounds, xdraw.Src, nil)
+	case FitContain:
+		scale := math.Min(float64(d.Width)/width, float64(d.Height)/height)
+		size := image.Pt(int(math.Round(width*scale)), int(math.Round(height*scale)))
+		min := image.Pt((d.Width-size.X)/2, (d.Height-size.Y)/2)
+		xdraw.CatmullRom.Scale(img, image.Rectangle{min, min.Add(size)}, src, bounds, xdraw.Src, nil)
+	case FitTile:
+		for y := 0; y < d.Height; y += bounds.Dy() {
+			for x := 0; x < d.Width; x += bounds.Dx() {
+				draw.Draw(img, bounds.Sub(bounds.Min).Add(image.Pt(x, y)), src, bounds.Min, draw.Src)
+			}
+		}
+	case FitCenter:
+		offset := image.Pt(
+			focalOffset(d.Width, bounds.Dx(), d.FocalPoint.X),
+			focalOffset(d.Height, bounds.Dy(), d.FocalPoint.Y),
+		)
+		draw.Draw(img, bounds.Sub(bounds.Min).Add(offset), src, bounds.Min, draw.Src)
+	default:
+		// Crop the part of the image with the aspect ratio of the canvas, around the focal point
+		scale := math.Max(float64(d.Width)/width, float64(d.Height)/height)
+		crop := image.Pt(int(math.Round(float64(d.Width)/scale)), int(math.Round(float64(d.Height)/scale)))
+		min := bounds.Min.Sub(image.Pt(
+			focalOffset(crop.X, bounds.Dx(), d.FocalPoint.X),
+			focalOffset(crop.Y, bounds.Dy(), d.FocalPoint.Y),
+		))
+		xdraw.CatmullRom.Scale(img, canvas, src, image.Rectangle{min, min.Add(crop)}.Intersect(bounds), xdraw.Src, nil)
+	}
+
+	if d.fitted == nil {
+		d.fitted = make(map[fitKey]*image.RGBA)
+	}
+	d.fitted[key] = img
+	return img
+}
+
+// fitKey identifies a background image fitted to a canvas
+type fitKey struct {
+	src        image.Image
+	fit        Fit
+	focalPoint FocalPoint
+	width      int
+	height     int
+}
+
+// focalOffset returns the offset of an image of the given size in a frame, centered when it fits in the frame,
+// otherwise putting focal, as a fraction of the size of the image, as close to the center of the frame as possible
+func focalOffset(frame, size int, focal float64) int {
+	if size <= frame {
+		return (frame - size) / 2
+	}
+	offset := int(math.Round(float64(frame)/2 - focal*float64(size)))
+	if offset > 0 {
+		return 0
+	}
+	if offset < frame-size {
+		return frame - size
+	}
+	return offset
+}
+
+// backgroundImagePaths returns the images a background image path stands for: the file itself,
+// the PNG and JPEG files of a directory sorted by name, or the files matching a glob like "photos/*.jpg"
+func backgroundImagePaths(path string) ([]string, error) {
+	paths := make([]string, 0)
+	if strings.ContainsAny(path, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	} else if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if !info.IsDir() {
+		return []string{path}, nil
+	} else {
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			switch strings.ToLower(filepath.Ext(file.Name())) {
+			case ".png", ".jpg", ".jpeg":
+				if !file.IsDir() {
+					paths = append(paths, filepath.Join(path, file.Name()))
+				}
+			}
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no background image in %s", path)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// loadBackgroundImage returns the image at path, decoding it only the first time it is used
+func (d *drawer) loadBackgroundImage(path string) (image.Image, error) {
+	if img, ok := d.backgroundImages[path]; ok {
+		return img, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, err
+	}
+	if d.backgroundImages == nil {
+		d.backgroundImages = make(map[string]image.Image)
+	}
+	d.backgroundImages[path] = img
+	return img, nil
+}
+
+// pickBackgroundImage returns the background image of a snippet: the one of its BACKGROUND_IMAGE directive,
+// or the next background image of the drawer in its image order. Directive paths may be relative to the folder
+// of the background images.
+func (d *drawer) pickBackgroundImage(options SnippetOptions) image.Image {
+	if options.BackgroundImage != "" {
+		path := options.BackgroundImage
+		if _, err := os.Stat(path); err != nil && len(d.BackgroundImagePaths) > 0 && !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(d.BackgroundImagePaths[0]), path)
+		}
+		img, err := d.loadBackgroundImage(path)
+		if err == nil {
+			return img
+		}
+		fmt.Printf("IGNORING background image <<%s>>: %s\n", options.BackgroundImage, err.Error())
+	}
+	if len(d.BackgroundImagePaths) == 0 {
+		return nil
+	}
+
+	index := 0
+	if d.BackgroundOrder == ImageRandom {
+		if d.imageRand == nil {
+			d.seedColors("")
+		}
+		index = d.imageRand.Intn(len(d.BackgroundImagePaths))
+	} else {
+		index = d.imageIndex % len(d.BackgroundImagePaths)
+		d.imageIndex++
+	}
+	img, err := d.loadBackgroundImage(d.BackgroundImagePaths[index])
+	if err != nil {
+		fmt.Printf("IGNORING background image <<%s>>: %s\n", d.BackgroundImagePaths[index], err.Error())
+	}
+	return img
+}
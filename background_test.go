@@ -0,0 +1,139 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFittedBackground(t *testing.T) {
+	red, blue := color.RGBA{255, 0, 0, 255}, color.RGBA{0, 0, 255, 255}
+	// A 400x100 image, red on its left half and blue on its right half
+	src := image.NewRGBA(image.Rect(0, 0, 400, 100))
+	draw.Draw(src, image.Rect(0, 0, 200, 100), image.NewUniform(red), image.ZP, draw.Src)
+	draw.Draw(src, image.Rect(200, 0, 400, 100), image.NewUniform(blue), image.ZP, draw.Src)
+
+	tests := []struct {
+		fit        Fit
+		focalPoint *FocalPoint
+		width      int
+		height     int
+		at         image.Point
+		expected   color.RGBA
+	}{
+		{FitCover, &FocalPoint{0.2, 0.5}, 100, 100, image.Pt(99, 50), red},
+		{FitCover, &FocalPoint{0.8, 0.5}, 100, 100, image.Pt(0, 50), blue},
+		{FitCover, nil, 100, 100, image.Pt(40, 50), red},
+		{FitCover, nil, 100, 100, image.Pt(60, 50), blue},
+		{FitContain, nil, 200, 200, image.Pt(100, 10), color.RGBA{}},
+		{FitContain, nil, 200, 200, image.Pt(10, 100), red},
+		{FitStretch, nil, 100, 400, image.Pt(90, 10), blue},
+		{FitTile, nil, 1000, 100, image.Pt(410, 50), red},
+		{FitTile, nil, 1000, 100, image.Pt(610, 50), blue},
+		{FitCenter, nil, 100, 200, image.Pt(49, 10), color.RGBA{}},
+		{FitCenter, nil, 100, 200, image.Pt(49, 100), red},
+		{FitCenter, &FocalPoint{0.9, 0.5}, 100, 200, image.Pt(0, 100), blue},
+		{FitCover, &FocalPoint{0, 0}, 100, 100, image.Pt(99, 50), red},
+		{FitCenter, &FocalPoint{0, 0}, 100, 200, image.Pt(99, 100), red},
+	}
+	for _, test := range tests {
+		d := &drawer{}
+		d.SetSize(test.width, test.height)
+		d.SetBackgroundFit(test.fit, test.focalPoint)
+		img := d.fittedBackground(src)
+		if size := img.Bounds().Size(); size != image.Pt(test.width, test.height) {
+			t.Errorf("%s: canvas must be %dx%d, got %v", test.fit, test.width, test.height, size)
+		}
+		if c := img.RGBAAt(test.at.X, test.at.Y); c != test.expected {
+			t.Errorf("%s %v: expected %v at %v, got %v", test.fit, test.focalPoint, test.expected, test.at, c)
+		}
+		if d.fittedBackground(src) != img {
+			t.Errorf("%s: fitted backgrounds must be cached", test.fit)
+		}
+	}
+
+	d := &drawer{}
+	if err := d.SetBackgroundFit("contian", nil); err == nil {
+		t.Error("unknown fits must be rejected")
+	}
+	if _, err := NewDrawer(Params{BackgroundFit: "contian"}); err == nil {
+		t.Error("drawers with an unknown fit must not be created")
+	}
+}
+
+func TestBackgroundImages(t *testing.T) {
+	dir, err := ioutil.TempDir("", "text2img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"b.png", "a.png", "c.jpg"} {
+		writeImage(image.NewRGBA(image.Rect(0, 0, 4, 4)), filepath.Join(dir, name))
+	}
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not an image"), 0644)
+
+	paths, err := backgroundImagePaths(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{filepath.Join(dir, "a.png"), filepath.Join(dir, "b.png"), filepath.Join(dir, "c.jpg")}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("directories must stand for their images sorted by name, got %q", paths)
+	}
+	if paths, _ := backgroundImagePaths(filepath.Join(dir, "*.png")); len(paths) != 2 {
+		t.Errorf("globs must stand for the files they match, got %q", paths)
+	}
+	if _, err := backgroundImagePaths(filepath.Join(dir, "*.gif")); err == nil {
+		t.Error("paths without images must be an error")
+	}
+
+	d := &drawer{}
+	if err := d.SetBackgroundImage(dir); err != nil {
+		t.Fatal(err)
+	}
+	d.SetBackgroundOrder("")
+	picked := make([]image.Image, 0)
+	for i := 0; i < 4; i++ {
+		picked = append(picked, d.pickBackgroundImage(SnippetOptions{}))
+	}
+	if picked[0] != d.backgroundImages[paths[0]] || picked[2] != d.backgroundImages[paths[2]] {
+		t.Error("images must be assigned in the order of their names")
+	}
+	if picked[3] != picked[0] {
+		t.Error("images must be assigned again after the last one, without being decoded again")
+	}
+	if img := d.pickBackgroundImage(SnippetOptions{BackgroundImage: "b.png"}); img != picked[1] {
+		t.Error("directive paths must be relative to the folder of the background images")
+	}
+	if img := d.pickBackgroundImage(SnippetOptions{BackgroundImage: "missing.png"}); img != picked[1] {
+		t.Error("missing directive images must fall back to the next background image")
+	}
+
+	d.SetBackgroundOrder(ImageRandom)
+	d.seedColors("notes")
+	first := []image.Image{d.pickBackgroundImage(SnippetOptions{}), d.pickBackgroundImage(SnippetOptions{})}
+	d.seedColors("notes")
+	if d.pickBackgroundImage(SnippetOptions{}) != first[0] || d.pickBackgroundImage(SnippetOptions{}) != first[1] {
+		t.Error("random images must be the same for the same notes")
+	}
+
+	d.SetColorStrategy(ColorRandom, 0)
+	d.seedColors("notes")
+	indices := []int{d.pickColor(nil), d.pickColor(nil)}
+	d.seedColors("notes")
+	if d.pickColor(nil); d.pickBackgroundImage(SnippetOptions{}) != first[0] || d.pickColor(nil) != indices[1] {
+		t.Error("random images must not change the colors")
+	}
+
+	if err := d.SetBackgroundOrder("shuffle"); err == nil || d.BackgroundOrder != ImageRandom {
+		t.Errorf("unknown orders must be rejected, got %v", err)
+	}
+	if _, err := NewDrawer(Params{BackgroundOrder: "shuffle"}); err == nil {
+		t.Error("drawers with an unknown order must not be created")
+	}
+}
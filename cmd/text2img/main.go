@@ -2,36 +2,58 @@ package main
 
 import (
 	"flag"
-	"image/jpeg"
+	"image"
 	"os"
 
 	"github.com/Iwark/text2img"
 )
 
+var paletteName = flag.String("palette", "", "name of a palette or path to a JSON, CSV or GIMP palette file")
+var colorStrategy = flag.String("colors", "random", "how colors are picked from the palette: random, distinct, round-robin, fixed or hash")
+var seed = flag.Int64("seed", 0, "seed of random colors, derived from the text when 0")
+var minContrast = flag.Float64("contrast", 4.5, "WCAG contrast ratio text must have with its background, negative to disable")
 var fontPath = flag.String("fontpath", "", "path to the font")
-var backgroundImagePath = flag.String("bgimg", "", "path to the background image")
-var output = flag.String("output", "image.jpg", "path to the output image")
+var backgroundImagePath = flag.String("bgimg", "", "path to the background image, or to a folder or glob of background images")
+var backgroundOrder = flag.String("bgorder", "round-robin", "order the background images are assigned to the images in: round-robin or random")
+var fit = flag.String("fit", "cover", "how the background image is fitted to the image: cover, contain, stretch, tile or center")
+var width = flag.Int("width", 1200, "width of the images")
+var height = flag.Int("height", 630, "height of the images")
+var overlay = flag.String("overlay", "", "overlay keeping text readable over the background image: dim, scrim, blur or box")
+var stroke = flag.Float64("stroke", 0, "width of the outline drawn around text in pixels")
+var shadow = flag.Float64("shadow", 0, "blur radius of the shadow drawn under text in pixels, offset down by a third of it")
+var output = flag.String("output", ".", "folder the images are written to")
+var theme = flag.String("theme", "", "name of a built-in theme (midnight, paper, shorts) or path to a JSON or YAML theme file")
 var text = flag.String("text", "", "text to draw")
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "palette" {
+		palette(os.Args[2:])
+		return
+	}
+
 	flag.Parse()
 	d, err := text2img.NewDrawer(text2img.Params{
+		Theme:               *theme,
+		PaletteName:         *paletteName,
+		ColorStrategy:       text2img.ColorStrategy(*colorStrategy),
+		Seed:                *seed,
+		MinContrast:         *minContrast,
 		FontPath:            *fontPath,
 		BackgroundImagePath: *backgroundImagePath,
+		BackgroundOrder:     text2img.ImageOrder(*backgroundOrder),
+		BackgroundFit:       text2img.Fit(*fit),
+		Width:               *width,
+		Height:              *height,
+		Overlay:             text2img.Overlay{Kind: text2img.OverlayKind(*overlay)},
+		OutputFolder:        *output,
+		TextStyle: text2img.TextStyle{
+			StrokeWidth:  *stroke,
+			ShadowOffset: image.Pt(0, int(*shadow/3)),
+			ShadowBlur:   *shadow,
+		},
 	})
 	if err != nil {
 		panic(err.Error())
 	}
-	img, err := d.Draw(*text)
-	if err != nil {
-		panic(err.Error())
-	}
-	file, err := os.Create(*output)
-	if err != nil {
-		panic(err.Error())
-	}
-	defer file.Close()
-	if err = jpeg.Encode(file, img, &jpeg.Options{Quality: 100}); err != nil {
-		panic(err.Error())
-	}
+	d.Draw(*text)
 }
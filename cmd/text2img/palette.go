@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/png"
+	"os"
+
+	"github.com/Iwark/text2img"
+)
+
+// palette renders the swatch sheet of a palette: text2img palette [-output palette.png] [name or file]
+func palette(args []string) {
+	flags := flag.NewFlagSet("palette", flag.ExitOnError)
+	output := flags.String("output", "palette.png", "path to the swatch sheet")
+	minContrast := flags.Float64("contrast", 4.5, "WCAG contrast ratio the pairs are checked against")
+	flags.Parse(args)
+
+	name := "default"
+	if flags.NArg() > 0 {
+		name = flags.Arg(0)
+	}
+	colors, err := text2img.FindPalette(name)
+	if err != nil {
+		panic(err.Error())
+	}
+	for _, failure := range text2img.CheckPalette(colors, *minContrast) {
+		fmt.Println(failure.String())
+	}
+	img, err := text2img.DrawPalette(colors)
+	if err != nil {
+		panic(err.Error())
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		panic(err.Error())
+	}
+	defer file.Close()
+	if err = png.Encode(file, img); err != nil {
+		panic(err.Error())
+	}
+}
@@ -0,0 +1,68 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// CodePanel is the look of code snippets, whose lines are drawn left aligned on a panel with rounded corners
+type CodePanel struct {
+	// Background is the color of the panel, no panel is drawn when it is transparent
+	Background color.RGBA
+	// TextColor is the color of code, the text color of the image when transparent
+	TextColor color.RGBA
+	// Padding and Radius are the space around code and the radius of the corners of the panel,
+	// as multiples of the font size
+	Padding float64
+	Radius  float64
+}
+
+// codePadding returns the space kept around code at the given font size
+func (d *drawer) codePadding(size float64) fixed.Int26_6 {
+	if d.CodePanel.Background.A == 0 {
+		return 0
+	}
+	return fixed.Int26_6(d.CodePanel.Padding * size * 64)
+}
+
+// drawCode draws the lines of a code snippet left aligned with each other, the block being placed by align
+func (d *drawer) drawCode(dst draw.Image, lines [][]span, baselines []fixed.Int26_6, top fixed.Int26_6, align Align) {
+	if len(lines) == 0 {
+		return
+	}
+	widest, width := 0, fixed.Int26_6(0)
+	for index, line := range lines {
+		if lineWidth := d.measureSpans(line, d.FontSize); lineWidth > width {
+			widest, width = index, lineWidth
+		}
+	}
+	x, _ := d.lineStart(lines[widest], align, true)
+
+	if d.CodePanel.Background.A > 0 {
+		metrics := d.face(d.styleFont(span{code: true}), d.FontSize).Metrics()
+		padding := d.codePadding(d.FontSize)
+		rect := image.Rect(
+			(x - padding).Floor(),
+			(top + baselines[0] - metrics.Ascent - padding).Floor(),
+			(x + width + padding).Ceil(),
+			(top + baselines[len(baselines)-1] + metrics.Descent + padding).Ceil(),
+		)
+		drawRoundedRect(dst, rect, d.CodePanel.Radius*d.FontSize, image.NewUniform(d.CodePanel.Background))
+	}
+
+	var textColor *image.Uniform
+	if d.CodePanel.TextColor.A > 0 {
+		textColor = image.NewUniform(d.CodePanel.TextColor)
+	}
+	for index, line := range lines {
+		for i := range line {
+			if textColor != nil && line[i].color == nil {
+				line[i].color = textColor
+			}
+		}
+		d.drawSpans(dst, line, d.FontSize, fixed.Point26_6{X: x, Y: top + baselines[index]}, 0)
+	}
+}
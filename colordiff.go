@@ -0,0 +1,101 @@
+package text2img
+
+import (
+	"image/color"
+	"math"
+)
+
+// lab is a color in the CIELAB space, under the D65 illuminant
+type lab struct {
+	L, A, B float64
+}
+
+// toLab converts an sRGB color to CIELAB
+func toLab(c color.RGBA) lab {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.04045 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	r, g, b := linear(c.R), linear(c.G), linear(c.B)
+	x := (0.4124564*r + 0.3575761*g + 0.1804375*b) / 0.95047
+	y := 0.2126729*r + 0.7151522*g + 0.0721750*b
+	z := (0.0193339*r + 0.1191920*g + 0.9503041*b) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+	fx, fy, fz := f(x), f(y), f(z)
+	return lab{116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)}
+}
+
+// deltaE returns the CIEDE2000 perceptual difference of two colors. A difference
+// around 2 is barely noticeable, above 10 colors look clearly different.
+func deltaE(c1, c2 color.RGBA) float64 {
+	return ciede2000(toLab(c1), toLab(c2))
+}
+
+func ciede2000(c1, c2 lab) float64 {
+	const pow25to7 = 6103515625.0
+	deg := math.Pi / 180
+
+	cBar := (math.Hypot(c1.A, c1.B) + math.Hypot(c2.A, c2.B)) / 2
+	g := 0.5 * (1 - math.Sqrt(math.Pow(cBar, 7)/(math.Pow(cBar, 7)+pow25to7)))
+	a1, a2 := (1+g)*c1.A, (1+g)*c2.A
+	chroma1, chroma2 := math.Hypot(a1, c1.B), math.Hypot(a2, c2.B)
+	hue := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / deg
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1, h2 := hue(c1.B, a1), hue(c2.B, a2)
+
+	deltaL := c2.L - c1.L
+	deltaC := chroma2 - chroma1
+	deltaH := 0.0
+	if chroma1*chroma2 != 0 {
+		dh := h2 - h1
+		if dh > 180 {
+			dh -= 360
+		} else if dh < -180 {
+			dh += 360
+		}
+		deltaH = 2 * math.Sqrt(chroma1*chroma2) * math.Sin(dh/2*deg)
+	}
+
+	lBar := (c1.L + c2.L) / 2
+	chromaBar := (chroma1 + chroma2) / 2
+	hBar := h1 + h2
+	if chroma1*chroma2 != 0 {
+		switch {
+		case math.Abs(h1-h2) <= 180:
+			hBar /= 2
+		case h1+h2 < 360:
+			hBar = (hBar + 360) / 2
+		default:
+			hBar = (hBar - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hBar-30)*deg) + 0.24*math.Cos(2*hBar*deg) +
+		0.32*math.Cos((3*hBar+6)*deg) - 0.20*math.Cos((4*hBar-63)*deg)
+	deltaTheta := 30 * math.Exp(-math.Pow((hBar-275)/25, 2))
+	rc := 2 * math.Sqrt(math.Pow(chromaBar, 7)/(math.Pow(chromaBar, 7)+pow25to7))
+	sl := 1 + 0.015*math.Pow(lBar-50, 2)/math.Sqrt(20+math.Pow(lBar-50, 2))
+	sc := 1 + 0.045*chromaBar
+	sh := 1 + 0.015*chromaBar*t
+	rt := -math.Sin(2*deltaTheta*deg) * rc
+
+	l, c, h := deltaL/sl, deltaC/sc, deltaH/sh
+	return math.Sqrt(l*l + c*c + h*h + rt*c*h)
+}
@@ -1,9 +1,10 @@
 package text2img
 
 import (
+	"hash/fnv"
 	"image/color"
 	"math/rand"
-	"time"
+	"strings"
 )
 
 // Color contains a good conbination of backgroundColor and textColor
@@ -15,7 +16,6 @@ type Color struct {
 var colors []Color
 
 func init() {
-	rand.Seed(time.Now().UnixNano())
 	g333 := must(Hex("#333"))
 	fff := must(Hex("#fff"))
 	colors = []Color{
@@ -79,9 +79,152 @@ func init() {
 		Color{must(Hex("#4b194c")), fff},
 		Color{must(Hex("#872b76")), fff},
 	}
+	Palettes["default"] = colors
 }
 
-// PickColor picks a color
+// ColorStrategy is how the colors of each snippet are picked from the palette
+type ColorStrategy string
+
+// Color strategies
+const (
+	// ColorRandom picks colors at random, from the seed of the drawer or the hash of the notes
+	ColorRandom ColorStrategy = "random"
+	// ColorRoundRobin picks the colors of the palette in order
+	ColorRoundRobin ColorStrategy = "round-robin"
+	// ColorFixed always picks the first color of the palette
+	ColorFixed ColorStrategy = "fixed"
+	// ColorDistinct draws the palette in a random order without repeating a color before all of them are used,
+	// each background being at least MinColorDistance away from the previous one when possible
+	ColorDistinct ColorStrategy = "distinct"
+	// ColorHash picks colors from the hash of the text of the snippet, so a snippet keeps its colors across builds
+	ColorHash ColorStrategy = "hash"
+)
+
+// PickColor picks one of the built-in colors from the global source of math/rand, so its picks are not reproducible.
+// Drawers pick their colors from a source of their own, seeded by Params.Seed or the notes.
 func PickColor() Color {
 	return colors[rand.Intn(len(colors))]
 }
+
+// PickAccentColor picks the background of the palette farthest from the background of c
+// among those sharing its text color, so that text stays readable on the accent
+func PickAccentColor(c Color) color.RGBA {
+	return pickAccentColor(colors, c)
+}
+
+func pickAccentColor(palette []Color, c Color) color.RGBA {
+	accent := c.TextColor
+	maxDistance := -1.0
+	for _, candidate := range palette {
+		if candidate.TextColor != c.TextColor {
+			continue
+		}
+		if distance := colorDistance(candidate.BackgroundColor, c.BackgroundColor); distance > maxDistance {
+			accent = candidate.BackgroundColor
+			maxDistance = distance
+		}
+	}
+	return accent
+}
+
+// palette returns the palette of the drawer, or the built-in colors when it has none
+func (d *drawer) palette() []Color {
+	if len(d.Palette) == 0 {
+		return colors
+	}
+	return d.Palette
+}
+
+// seedColors resets the random sources of colors and background images and the position in the palette, seeded
+// from the seed of the drawer or from the hash of text when it has none, so that the same notes are drawn the same
+func (d *drawer) seedColors(text string) {
+	seed := d.Seed
+	if seed == 0 {
+		h := fnv.New64a()
+		h.Write([]byte(text))
+		seed = int64(h.Sum64())
+	}
+	d.rand = rand.New(rand.NewSource(seed))
+	// Background images are picked from a source of their own, so that they don't change the colors
+	d.imageRand = rand.New(rand.NewSource(seed))
+	d.colorIndex = 0
+	d.colorBag = nil
+	d.previousColor = nil
+}
+
+// pickColor picks the index in the palette of the colors of a snippet made of lines
+// following the color strategy of the drawer
+func (d *drawer) pickColor(lines []string) int {
+	palette := d.palette()
+	switch d.ColorStrategy {
+	case ColorRoundRobin:
+		index := d.colorIndex % len(palette)
+		d.colorIndex++
+		return index
+	case ColorFixed:
+		return 0
+	case ColorHash:
+		h := fnv.New32a()
+		h.Write([]byte(strings.Join(lines, "\n")))
+		return int(h.Sum32() % uint32(len(palette)))
+	}
+	if d.rand == nil {
+		d.seedColors("")
+	}
+	if d.ColorStrategy == ColorDistinct {
+		return d.pickDistinctColor(palette)
+	}
+	return d.rand.Intn(len(palette))
+}
+
+// pickDistinctColor takes the index of the next color out of a shuffled bag of the palette, skipping the colors too close
+// to the previous background. The bag is refilled once empty, so every color is used before any repeats.
+func (d *drawer) pickDistinctColor(palette []Color) int {
+	if len(d.colorBag) == 0 {
+		d.colorBag = d.rand.Perm(len(palette))
+	}
+
+	// Fall back to the farthest color of the bag when none is far enough
+	pick, farthest := 0, -1.0
+	for i := 0; i < len(d.colorBag) && d.previousColor != nil; i++ {
+		distance := deltaE(palette[d.colorBag[i]].BackgroundColor, d.previousColor.BackgroundColor)
+		if distance >= d.MinColorDistance {
+			pick = i
+			break
+		}
+		if distance > farthest {
+			pick, farthest = i, distance
+		}
+	}
+
+	index := d.colorBag[pick]
+	d.colorBag = append(d.colorBag[:pick], d.colorBag[pick+1:]...)
+	d.previousColor = &palette[index]
+	return index
+}
+
+// pickAccentColor picks the accent color of the drawer farthest from the background of c,
+// or the accent of the palette of the drawer when it has no accent colors
+func (d *drawer) pickAccentColor(c Color) color.RGBA {
+	if len(d.AccentColors) == 0 && len(d.Palette) == 0 {
+		return PickAccentColor(c)
+	}
+	if len(d.AccentColors) == 0 {
+		return pickAccentColor(d.Palette, c)
+	}
+	accent := d.AccentColors[0]
+	for _, candidate := range d.AccentColors[1:] {
+		if colorDistance(candidate, c.BackgroundColor) > colorDistance(accent, c.BackgroundColor) {
+			accent = candidate
+		}
+	}
+	return accent
+}
+
+// colorDistance returns the squared euclidean distance of two colors in RGB space
+func colorDistance(c1, c2 color.RGBA) float64 {
+	dr := float64(c1.R) - float64(c2.R)
+	dg := float64(c1.G) - float64(c2.G)
+	db := float64(c1.B) - float64(c2.B)
+	return dr*dr + dg*dg + db*db
+}
@@ -0,0 +1,119 @@
+package text2img
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestPickColorStrategies(t *testing.T) {
+	snippets := [][]string{{"First slide."}, {"Second slide."}, {"Third slide."}, {"Fourth slide."}}
+	pick := func(strategy ColorStrategy, seed int64, text string) []Color {
+		d := &drawer{}
+		d.SetColorStrategy(strategy, seed)
+		d.seedColors(text)
+		picked := make([]Color, 0, len(snippets))
+		for _, lines := range snippets {
+			picked = append(picked, d.palette()[d.pickColor(lines)])
+		}
+		return picked
+	}
+
+	if !reflect.DeepEqual(pick(ColorRandom, 42, "notes"), pick(ColorRandom, 42, "other notes")) {
+		t.Errorf("random colors must only depend on the seed")
+	}
+	if !reflect.DeepEqual(pick(ColorRandom, 0, "notes"), pick(ColorRandom, 0, "notes")) {
+		t.Errorf("random colors of the same notes must be the same without a seed")
+	}
+	if reflect.DeepEqual(pick(ColorRandom, 1, ""), pick(ColorRandom, 2, "")) {
+		t.Errorf("random colors must change with the seed")
+	}
+
+	if picked := pick(ColorRoundRobin, 0, ""); !reflect.DeepEqual(picked, colors[:4]) {
+		t.Errorf("round-robin must pick the palette in order, got %v", picked)
+	}
+	for _, c := range pick(ColorFixed, 0, "") {
+		if c != colors[0] {
+			t.Errorf("fixed must always pick the first color, got %v", c)
+		}
+	}
+
+	d := &drawer{}
+	d.SetColorStrategy(ColorHash, 0)
+	if colors[d.pickColor(snippets[2])] != pick(ColorHash, 7, "")[2] {
+		t.Errorf("hash must pick the colors of a snippet from its text only")
+	}
+	if err := d.SetColorStrategy("rainbow", 0); err == nil || d.ColorStrategy != ColorHash {
+		t.Errorf("unknown strategies must be rejected, got %v", err)
+	}
+	if _, err := NewDrawer(Params{ColorStrategy: "rainbow"}); err == nil {
+		t.Errorf("drawers with an unknown strategy must not be created")
+	}
+}
+
+func TestDeltaE(t *testing.T) {
+	// Pairs from Sharma, Wu and Dalal, "The CIEDE2000 color-difference formula"
+	tests := []struct {
+		c1, c2 lab
+		deltaE float64
+	}{
+		{lab{50, 2.6772, -79.7751}, lab{50, 0, -82.7485}, 2.0425},
+		{lab{50, 0, 0}, lab{50, -1, 2}, 2.3669},
+		{lab{50, 2.5, 0}, lab{73, 25, -18}, 27.1492},
+		{lab{2.0776, 0.0795, -1.1350}, lab{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+	for _, test := range tests {
+		if deltaE := ciede2000(test.c1, test.c2); math.Abs(deltaE-test.deltaE) > 0.0001 {
+			t.Errorf("expected %v between %v and %v, got %v", test.deltaE, test.c1, test.c2, deltaE)
+		}
+	}
+	if l := toLab(must(Hex("#fff"))); math.Abs(l.L-100) > 0.01 || math.Abs(l.A) > 0.01 || math.Abs(l.B) > 0.01 {
+		t.Errorf("white must be L=100 a=0 b=0, got %+v", l)
+	}
+}
+
+func TestPickDistinctColors(t *testing.T) {
+	d := &drawer{}
+	d.SetColorStrategy(ColorDistinct, 3)
+	d.SetMinColorDistance(15)
+
+	seen := make(map[Color]int)
+	for i := 0; i < 2*len(colors); i++ {
+		c := colors[d.pickColor(nil)]
+		if i < len(colors) && seen[c] > 0 {
+			t.Errorf("%v must not repeat before the whole palette is used", c)
+		}
+		seen[c]++
+	}
+	for _, c := range colors {
+		if seen[c] != 2 {
+			t.Errorf("every color must be used once per cycle, %v was used %d times", c, seen[c])
+		}
+	}
+
+	for seed := int64(1); seed <= 50; seed++ {
+		d.SetColorStrategy(ColorDistinct, seed)
+		d.seedColors("")
+		previous := d.pickColor(nil)
+		for i := 1; i < 2*len(colors); i++ {
+			bag := append([]int(nil), d.colorBag...)
+			index := d.pickColor(nil)
+			if len(bag) == 0 {
+				// The bag was refilled by the pick
+				bag = append(append(bag, d.colorBag...), index)
+			}
+			distance := deltaE(colors[index].BackgroundColor, colors[previous].BackgroundColor)
+			if distance < d.MinColorDistance {
+				// Closer colors are only picked when none of the bag is far enough, the farthest one first
+				for _, candidate := range bag {
+					if other := deltaE(colors[candidate].BackgroundColor, colors[previous].BackgroundColor); other > distance {
+						t.Errorf("seed %d: %v is only %.1f from %v while %v is %.1f from it", seed,
+							colors[index].BackgroundColor, distance, colors[previous].BackgroundColor, colors[candidate].BackgroundColor, other)
+						break
+					}
+				}
+			}
+			previous = index
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package text2img
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+)
+
+// ContrastFailure is a pair of text and background colors below the minimum contrast ratio
+type ContrastFailure struct {
+	Text       color.RGBA
+	Background color.RGBA
+	Ratio      float64
+	// Source tells where the pair comes from: "palette", "SetColors", "background image" or "background pattern"
+	Source string
+	// Corrected is the text color used instead
+	Corrected color.RGBA
+}
+
+func (f ContrastFailure) String() string {
+	return fmt.Sprintf("%s on %s (%s): contrast %.2f:1, text switched to %s",
+		HexString(f.Text), HexString(f.Background), f.Source, f.Ratio, HexString(f.Corrected))
+}
+
+// relativeLuminance returns the WCAG relative luminance of c, between 0 for black and 1 for white
+func relativeLuminance(c color.RGBA) float64 {
+	linear := func(v uint8) float64 {
+		s := float64(v) / 255
+		if s <= 0.03928 {
+			return s / 12.92
+		}
+		return math.Pow((s+0.055)/1.055, 2.4)
+	}
+	return 0.2126*linear(c.R) + 0.7152*linear(c.G) + 0.0722*linear(c.B)
+}
+
+// ContrastRatio returns the WCAG contrast ratio of two colors, from 1:1 to 21:1
+func ContrastRatio(c1, c2 color.RGBA) float64 {
+	l1, l2 := relativeLuminance(c1), relativeLuminance(c2)
+	if l1 < l2 {
+		l1, l2 = l2, l1
+	}
+	return (l1 + 0.05) / (l2 + 0.05)
+}
+
+// CheckPalette returns the pairs of a palette whose contrast is below minContrast
+func CheckPalette(palette []Color, minContrast float64) []ContrastFailure {
+	failures := make([]ContrastFailure, 0)
+	for _, c := range palette {
+		if ratio := ContrastRatio(c.TextColor, c.BackgroundColor); ratio < minContrast {
+			failures = append(failures, ContrastFailure{
+				Text:       c.TextColor,
+				Background: c.BackgroundColor,
+				Ratio:      ratio,
+				Source:     "palette",
+				Corrected:  correctedTextColor(c.BackgroundColor, minContrast),
+			})
+		}
+	}
+	return failures
+}
+
+// checkContrast returns textColor, or the light or dark text color most readable on background
+// when their contrast is below the minimum ratio of the drawer. Failing pairs are recorded for the report.
+func (d *drawer) checkContrast(textColor, background color.RGBA, source string) color.RGBA {
+	if d.MinContrast <= 0 {
+		return textColor
+	}
+	ratio := ContrastRatio(textColor, background)
+	if ratio >= d.MinContrast {
+		return textColor
+	}
+	corrected := correctedTextColor(background, d.MinContrast)
+	failure := ContrastFailure{textColor, background, ratio, source, corrected}
+	if d.contrastFailures == nil {
+		d.contrastFailures = make(map[ContrastFailure]bool)
+	}
+	if !d.contrastFailures[failure] {
+		d.contrastFailures[failure] = true
+		d.contrastReport = append(d.contrastReport, failure)
+	}
+	return corrected
+}
+
+// correctedTextColor returns the light or dark text color most readable on background,
+// or pure black or white when it is still below minContrast
+func correctedTextColor(background color.RGBA, minContrast float64) color.RGBA {
+	corrected := readableTextColor(background)
+	if ContrastRatio(corrected, background) < minContrast {
+		// Pure black reaches higher ratios than the dark text of the palette
+		black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+		if corrected = white; ContrastRatio(black, background) > ContrastRatio(white, background) {
+			corrected = black
+		}
+	}
+	return corrected
+}
+
+// averageColor returns the average color of the part of img inside rect
+func averageColor(img image.Image, rect image.Rectangle) color.RGBA {
+	rect = rect.Intersect(img.Bounds())
+	// Sampling every few pixels is enough for an average
+	step := int(math.Max(1, math.Sqrt(float64(rect.Dx()*rect.Dy())/10000)))
+	var r, g, b, n uint64
+	for y := rect.Min.Y; y < rect.Max.Y; y += step {
+		for x := rect.Min.X; x < rect.Max.X; x += step {
+			c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			r, g, b, n = r+uint64(c.R), g+uint64(c.G), b+uint64(c.B), n+1
+		}
+	}
+	if n == 0 {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(b / n), 255}
+}
+
+// writeContrastReport writes a line per pair of colors that failed the contrast check
+func writeContrastReport(w io.Writer, failures []ContrastFailure, minContrast float64) error {
+	if _, err := fmt.Fprintf(w, "%d color pairs below the contrast ratio of %.1f:1\n", len(failures), minContrast); err != nil {
+		return err
+	}
+	for _, failure := range failures {
+		if _, err := fmt.Fprintln(w, failure.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package text2img
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		c1, c2 string
+		ratio  float64
+	}{
+		{"#000", "#fff", 21},
+		{"#fff", "#fff", 1},
+		{"#fff", "#777", 4.48},
+		{"#fff", "#ebb582", 1.83},
+	}
+	for _, test := range tests {
+		if ratio := ContrastRatio(must(Hex(test.c1)), must(Hex(test.c2))); math.Abs(ratio-test.ratio) > 0.01 {
+			t.Errorf("expected a ratio of %v between %s and %s, got %v", test.ratio, test.c1, test.c2, ratio)
+		}
+	}
+
+	failures := CheckPalette(colors, 4.5)
+	found := false
+	for _, failure := range failures {
+		if HexString(failure.Background) == "#ebb582" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("white on #ebb582 must fail the contrast check")
+	}
+	// The correction reported for the palette is the one drawn
+	gray := Color{BackgroundColor: must(Hex("#808080")), TextColor: must(Hex("#fff"))}
+	d := &drawer{}
+	d.SetMinContrast(4.5)
+	failures = CheckPalette([]Color{gray}, 4.5)
+	if drawn := d.checkContrast(gray.TextColor, gray.BackgroundColor, "palette"); len(failures) != 1 || failures[0].Corrected != drawn {
+		t.Errorf("palette checks must report the color drawn instead, %v, got %v", drawn, failures)
+	}
+}
+
+func TestCheckContrast(t *testing.T) {
+	white, dark := must(Hex("#fff")), must(Hex("#333"))
+	d := &drawer{}
+	d.SetMinContrast(0)
+
+	if c := d.checkContrast(white, must(Hex("#1e1f26")), "palette"); c != white || len(d.contrastReport) != 0 {
+		t.Errorf("readable text must be kept, got %v", c)
+	}
+	if c := d.checkContrast(white, must(Hex("#faaf08")), "palette"); c != dark {
+		t.Errorf("white on #faaf08 must switch to dark text, got %v", c)
+	}
+	d.checkContrast(white, must(Hex("#faaf08")), "palette")
+	if len(d.contrastReport) != 1 {
+		t.Errorf("failing pairs must be reported once, got %v", d.contrastReport)
+	}
+
+	// Mid gray reaches 4.5:1 with neither white nor #333, only with black
+	if c := d.checkContrast(white, must(Hex("#808080")), "SetColors"); ContrastRatio(c, must(Hex("#808080"))) < 4.5 {
+		t.Errorf("text must be corrected to a readable color, got %v", c)
+	}
+
+	var buf bytes.Buffer
+	if err := writeContrastReport(&buf, d.contrastReport, d.MinContrast); err != nil {
+		t.Fatal(err.Error())
+	}
+	if !strings.Contains(buf.String(), "#ffffff on #faaf08 (palette)") {
+		t.Errorf("report must list the failing pairs, got %q", buf.String())
+	}
+
+	d.SetMinContrast(-1)
+	if c := d.checkContrast(white, white, "SetColors"); c != white {
+		t.Errorf("a negative ratio must disable the check")
+	}
+}
+
+func TestAverageColor(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for x := 0; x < 50; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	for x := 50; x < 100; x++ {
+		for y := 0; y < 100; y++ {
+			img.Set(x, y, color.Black)
+		}
+	}
+	if c := averageColor(img, img.Bounds()); c.R < 126 || c.R > 128 {
+		t.Errorf("average of black and white halves must be mid gray, got %v", c)
+	}
+	if c := averageColor(img, image.Rect(0, 0, 50, 100)); c != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("average of the white half must be white, got %v", c)
+	}
+}
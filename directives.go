@@ -0,0 +1,92 @@
+package text2img
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SnippetOptions are the settings of a snippet given by directive lines in the notes
+type SnippetOptions struct {
+	// Duration is how long the snippet is shown in a frame sequence, in seconds
+	Duration float64
+	// Align and VerticalAlign override the alignments of the drawer
+	Align         Align
+	VerticalAlign VerticalAlign
+	// BackgroundImage is the path of the background image of the snippet
+	BackgroundImage string
+}
+
+// directives set an option from the value following their keyword, like "DURATION 4.5"
+var directives = map[string]func(*SnippetOptions, string) error{
+	"DURATION": func(options *SnippetOptions, value string) error {
+		duration, err := strconv.ParseFloat(value, 64)
+		if err != nil || duration <= 0 {
+			return fmt.Errorf("duration must be a positive number of seconds, got %q", value)
+		}
+		options.Duration = duration
+		return nil
+	},
+	"ALIGN": func(options *SnippetOptions, value string) error {
+		switch align := Align(strings.ToLower(value)); align {
+		case AlignCenter, AlignLeft, AlignRight, AlignJustify:
+			options.Align = align
+			return nil
+		}
+		return fmt.Errorf("alignment must be left, center, right or justify, got %q", value)
+	},
+	"VALIGN": func(options *SnippetOptions, value string) error {
+		switch verticalAlign := VerticalAlign(strings.ToLower(value)); verticalAlign {
+		case VerticalAlignTop, VerticalAlignMiddle, VerticalAlignBottom:
+			options.VerticalAlign = verticalAlign
+			return nil
+		}
+		return fmt.Errorf("vertical alignment must be top, middle or bottom, got %q", value)
+	},
+	"BACKGROUND_IMAGE": func(options *SnippetOptions, value string) error {
+		options.BackgroundImage = value
+		return nil
+	},
+}
+
+// applyDirective applies the directive of a line to options, and reports whether the line is a directive.
+// Lines starting with a directive keyword but without a valid value, like "ALIGN your goals", are text.
+func applyDirective(options *SnippetOptions, line string) bool {
+	fields := strings.SplitN(strings.Trim(line, " \t\n\r"), " ", 2)
+	directive, ok := directives[fields[0]]
+	if !ok || len(fields) < 2 {
+		return false
+	}
+	if err := directive(options, strings.Trim(fields[1], " \t\n\r")); err != nil {
+		fmt.Printf("IGNORING directive <<%s>>, drawing it as text: %s\n", line, err.Error())
+		return false
+	}
+	return true
+}
+
+// applyDirectives removes directive lines from the snippets and sets the options of the snippets they apply to.
+// Directives on their own apply to the next snippet, directives inside a text snippet apply to that snippet.
+func applyDirectives(snippets []Snippet) []Snippet {
+	applied := make([]Snippet, 0, len(snippets))
+	pending := SnippetOptions{}
+	for _, snippet := range snippets {
+		options := pending
+		if !snippet.Code {
+			lines := make([]string, 0, len(snippet.Lines))
+			for _, line := range snippet.Lines {
+				if !applyDirective(&options, line) {
+					lines = append(lines, line)
+				}
+			}
+			if len(lines) == 0 && len(snippet.Lines) > 0 {
+				pending = options
+				continue
+			}
+			snippet.Lines = lines
+		}
+		snippet.Options = options
+		pending = SnippetOptions{}
+		applied = append(applied, snippet)
+	}
+	return applied
+}
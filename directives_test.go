@@ -0,0 +1,36 @@
+package text2img
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyDirectives(t *testing.T) {
+	snippets := applyDirectives([]Snippet{
+		{Lines: []string{"DURATION 4.5"}},
+		{Lines: []string{"Shown for four seconds and a half."}},
+		{Lines: []string{"First line of a block.", "DURATION 2", "Second line of a block."}},
+		{Lines: []string{"DURATION 1"}, Code: true},
+		{Lines: []string{"Shown for the default duration."}},
+		{Lines: []string{"DURATION of the war was long", "ALIGN your goals first"}},
+	})
+
+	if len(snippets) != 5 {
+		t.Fatalf("directive only snippets must be removed, got %d snippets", len(snippets))
+	}
+	for i, duration := range []float64{4.5, 2, 0, 0, 0} {
+		if snippets[i].Options.Duration != duration {
+			t.Errorf("snippet %d must last %v, got %v", i, duration, snippets[i].Options.Duration)
+		}
+	}
+	if !reflect.DeepEqual(snippets[1].Lines, []string{"First line of a block.", "Second line of a block."}) {
+		t.Errorf("directive lines must be removed from blocks, got %q", snippets[1].Lines)
+	}
+	if !reflect.DeepEqual(snippets[2].Lines, []string{"DURATION 1"}) {
+		t.Errorf("code must be kept as it is, got %q", snippets[2].Lines)
+	}
+	if !reflect.DeepEqual(snippets[4].Lines, []string{"DURATION of the war was long", "ALIGN your goals first"}) ||
+		snippets[4].Options != (SnippetOptions{}) {
+		t.Errorf("lines with an invalid directive value must be kept as text, got %q", snippets[4].Lines)
+	}
+}
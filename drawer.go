@@ -6,18 +6,17 @@ import (
 	"image/color"
 	"image/draw"
 	"image/jpeg"
-	_ "image/png"
+	"image/png"
 	"io/ioutil"
 	"math"
+	"math/rand"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 
 	"github.com/golang/freetype"
 	"github.com/golang/freetype/truetype"
-	"golang.org/x/image/font"
-	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
 )
 
 // Drawer is the main interface for this package
@@ -32,10 +31,47 @@ type Drawer interface {
 
 // Params is parameters for NewDrawer function
 type Params struct {
+	Theme               string
 	Width               int
 	Height              int
 	FontPath            string
+	FallbackFontPaths   []string
+	BoldFontPath        string
+	ItalicFontPath      string
+	BoldItalicFontPath  string
+	CodeFontPath        string
+	EmojiDir            string
+	BlankWidth          float64
+	RevealBlanks        bool
+	Flashcards          bool
+	BuildFrames         bool
+	Transition          Transition
+	TransitionDuration  float64
+	FrameRate           float64
+	FrameSequence       bool
+	Duration            float64
+	Align               Align
+	VerticalAlign       VerticalAlign
+	Margins             Margins
+	SafeArea            string
+	LineHeight          float64
+	ParagraphSpacing    float64
+	CodePanel           CodePanel
+	Overlay             Overlay
+	TextStyle           TextStyle
+	Palette             []Color
+	PaletteName         string
+	PaletteBackgrounds  []*Background
+	ColorStrategy       ColorStrategy
+	MinColorDistance    float64
+	Seed                int64
+	MinContrast         float64
+	AccentColors        []color.RGBA
 	BackgroundImagePath string
+	BackgroundOrder     ImageOrder
+	BackgroundFit       Fit
+	FocalPoint          *FocalPoint
+	Background          Background
 	FontSize            float64
 	BackgroundColor     color.RGBA
 	TextColor           color.RGBA
@@ -48,9 +84,54 @@ type Params struct {
 // NewDrawer returns Drawer interface
 func NewDrawer(params Params) (Drawer, error) {
 	d := &drawer{}
-	
+
+	if params.PaletteName != "" && len(params.Palette) == 0 {
+		palette, backgrounds, err := findPalette(params.PaletteName)
+		if err != nil {
+			return d, err
+		}
+		params.Palette = palette
+		params.PaletteBackgrounds = backgrounds
+	}
+	if params.Theme != "" {
+		theme, err := FindTheme(params.Theme)
+		if err != nil {
+			return d, err
+		}
+		if params, err = theme.fill(params); err != nil {
+			return d, err
+		}
+	}
+
 	d.SetNotesSource(params.NotesSource)
 	d.SetOutputFolder(params.OutputFolder)
+	d.SetBlankWidth(params.BlankWidth)
+	d.SetRevealBlanks(params.RevealBlanks)
+	d.SetFlashcards(params.Flashcards)
+	d.SetBuildFrames(params.BuildFrames)
+	if err := d.SetTransition(params.Transition, params.TransitionDuration); err != nil {
+		return d, err
+	}
+	d.SetFrameRate(params.FrameRate)
+	d.SetFrameSequence(params.FrameSequence, params.Duration)
+	d.SetAlign(params.Align, params.VerticalAlign)
+	d.SetMargins(params.Margins)
+	d.SetLineSpacing(params.LineHeight, params.ParagraphSpacing)
+	d.SetCodePanel(params.CodePanel)
+	if err := d.SetOverlay(params.Overlay); err != nil {
+		return d, err
+	}
+	d.SetTextStyle(params.TextStyle)
+	d.SetPalette(params.Palette, params.AccentColors)
+	d.SetPaletteBackgrounds(params.PaletteBackgrounds)
+	if err := d.SetColorStrategy(params.ColorStrategy, params.Seed); err != nil {
+		return d, err
+	}
+	d.SetMinColorDistance(params.MinColorDistance)
+	d.SetMinContrast(params.MinContrast)
+	if err := d.SetSafeArea(params.SafeArea); err != nil {
+		return d, err
+	}
 
 	if params.FontPath != "" {
 		err := d.SetFontPath(params.FontPath)
@@ -58,15 +139,56 @@ func NewDrawer(params Params) (Drawer, error) {
 			return d, err
 		}
 	}
+	if len(params.FallbackFontPaths) > 0 {
+		err := d.SetFallbackFontPaths(params.FallbackFontPaths...)
+		if err != nil {
+			return d, err
+		}
+	}
+	if params.BoldFontPath != "" {
+		err := d.SetBoldFontPath(params.BoldFontPath)
+		if err != nil {
+			return d, err
+		}
+	}
+	if params.ItalicFontPath != "" {
+		err := d.SetItalicFontPath(params.ItalicFontPath)
+		if err != nil {
+			return d, err
+		}
+	}
+	if params.BoldItalicFontPath != "" {
+		err := d.SetBoldItalicFontPath(params.BoldItalicFontPath)
+		if err != nil {
+			return d, err
+		}
+	}
+	if params.CodeFontPath != "" {
+		err := d.SetCodeFontPath(params.CodeFontPath)
+		if err != nil {
+			return d, err
+		}
+	}
+	if params.EmojiDir != "" {
+		err := d.SetEmojiDir(params.EmojiDir)
+		if err != nil {
+			return d, err
+		}
+	}
 	if params.BackgroundImagePath != "" {
 		err := d.SetBackgroundImage(params.BackgroundImagePath)
 		if err != nil {
 			return d, err
 		}
-		d.SetSize(d.BackgroundImage.Bounds().Size().X, d.BackgroundImage.Bounds().Size().Y)
-	} else {
-		d.SetSize(params.Width, params.Height)
 	}
+	if err := d.SetBackgroundOrder(params.BackgroundOrder); err != nil {
+		return d, err
+	}
+	d.SetSize(params.Width, params.Height)
+	if err := d.SetBackgroundFit(params.BackgroundFit, params.FocalPoint); err != nil {
+		return d, err
+	}
+	d.SetBackground(params.Background)
 
 	// d.SetColors(params.TextColor, params.BackgroundColor)
 	// d.SetFontSize(params.FontSize)
@@ -77,17 +199,74 @@ func NewDrawer(params Params) (Drawer, error) {
 type drawer struct {
 	BackgroundColor   *image.Uniform
 	BackgroundImage   image.Image
+	BackgroundFit     Fit
+	FocalPoint        FocalPoint
+	Background        Background
 	Font              *truetype.Font
+	FallbackFonts     []*truetype.Font
+	BoldFont          *truetype.Font
+	ItalicFont        *truetype.Font
+	BoldItalicFont    *truetype.Font
+	CodeFont          *truetype.Font
 	FontSize          float64
 	Height            int
 	TextColor         *image.Uniform
+	AccentColor       *image.Uniform
 	TextPosVertical   int
 	TextPosHorizontal int
 	Width             int
 	NotesSource 	  string
 	OutputFolder	  string
+	BlankWidth        float64
+	RevealBlanks      bool
+	Flashcards        bool
+	BuildFrames       bool
+
+	Transition         Transition
+	TransitionDuration float64
+	FrameRate          float64
+	FrameSequence      bool
+	Duration           float64
+
+	Align         Align
+	VerticalAlign VerticalAlign
+	Margins       Margins
+	SafeArea      SafeArea
+
+	LineHeight       float64
+	ParagraphSpacing float64
+
+	CodePanel          CodePanel
+	Overlay            Overlay
+	TextStyle          TextStyle
+	Palette            []Color
+	PaletteBackgrounds []*Background
+	AccentColors       []color.RGBA
+
+	ColorStrategy    ColorStrategy
+	MinColorDistance float64
+	Seed             int64
+	rand             *rand.Rand
+	colorIndex       int
+	colorBag         []int
+	previousColor    *Color
+
+	MinContrast      float64
+	contrastFailures map[ContrastFailure]bool
+	contrastReport   []ContrastFailure
+
+	BackgroundImagePaths []string
+	BackgroundOrder      ImageOrder
+	backgroundImages     map[string]image.Image
+	imageIndex           int
+	imageRand            *rand.Rand
 
 	autoFontSize bool
+	emoji        *emojiSet
+	fitted       map[fitKey]*image.RGBA
+	patterns     map[patternKey]*image.RGBA
+	background   *Background
+	faceCache
 }
 
 // type NotesType int
@@ -210,13 +389,35 @@ func TerminateLineWithDotSpace(str string) (string) {
 	return str
 }
 
+// Snippet is a group of lines displayed in one image
+type Snippet struct {
+	Lines   []string
+	Code    bool
+	Options SnippetOptions
+}
+
+// Snippets returns the lines of each snippet of text
 func (d *drawer) Snippets(text string) ([][]string) {
+	return snippetLines(d.parseSnippets(text))
+}
+
+// snippetLines returns the lines of each snippet
+func snippetLines(snippets []Snippet) [][]string {
+	lines := make([][]string, 0, len(snippets))
+	for _, snippet := range snippets {
+		lines = append(lines, snippet.Lines)
+	}
+	return lines
+}
+
+// parseSnippets splits text into the snippets displayed in one image each, code snippets included
+func (d *drawer) parseSnippets(text string) ([]Snippet) {
 	codeSnippetStart := "{{{{{{"
 	codeSnippetEnd := "}}}}}}"
 	textSnippetStart := "[[[[[["
 	textSnippetEnd := "]]]]]]"
 
-	snippets := make([][]string, 0)
+	snippets := make([]Snippet, 0)
 
 	lines := Lines(text)
 
@@ -233,7 +434,7 @@ func (d *drawer) Snippets(text string) ([][]string) {
 
 		if line == codeSnippetEnd {
 			accumulateCodeSnippet = false
-			snippets = append(snippets, codeSnippet)
+			snippets = append(snippets, Snippet{Lines: codeSnippet, Code: true})
 			codeSnippet = make([]string, 0)
 			continue
 		}
@@ -251,7 +452,7 @@ func (d *drawer) Snippets(text string) ([][]string) {
 
 		if line == textSnippetEnd {
 			accumulateTextSnippet = false
-			snippets = append(snippets, textSnippet)
+			snippets = append(snippets, Snippet{Lines: textSnippet})
 			textSnippet = make([]string, 0)
 			continue
 		}
@@ -331,12 +532,12 @@ func (d *drawer) Snippets(text string) ([][]string) {
 		// If we are in the context of processing "Single Line Text".
 		// Processing of "Text Snippet Accumulation" is being handled in `textSnippetEnd` check above.
 		if accumulateTextSnippet == false {
-			snippets = append(snippets, textSnippet)
+			snippets = append(snippets, Snippet{Lines: textSnippet})
 			textSnippet = make([]string, 0)
 		}
 	}
 
-	PrintSnippets(snippets)
+	PrintSnippets(snippetLines(snippets))
 	return snippets
 }
 
@@ -390,150 +591,411 @@ func LeftPad2Len(s string, padStr string, overallLen int) string {
 
 // Draw returns the image of a text
 func (d *drawer) Draw(text string) {
-	snippets := d.Snippets(text)
+	frames := d.frames(applyDirectives(d.parseSnippets(text)))
+	fileNames := d.fileNames(frames)
+	d.seedColors(text)
+	d.imageIndex = 0
+	d.contrastFailures, d.contrastReport = nil, nil
 
-	overallLenForPadding := len(strconv.Itoa(len(snippets) - 1))
+	cardRows := make([]cardRow, 0)
 
-	for index, snippet := range snippets {
-		if len(snippet) == 0 {
+	// Transition frames are drawn once the image they lead to is drawn
+	var previous image.Image
+	transitions := make([]string, 0)
+
+	for index, frame := range frames {
+		if frame.transition > 0 {
+			transitions = append(transitions, fileNames[index]...)
 			continue
 		}
 
-		var bgColor, textColor color.RGBA
+		if len(frame.Lines) == 0 {
+			continue
+		}
 
-		//set unique random color every time
-		d.SetColors(bgColor, textColor)
-		//let it use auto font size
-		d.SetFontSize(0)
+		// The following frames of a snippet keep its colors and background image
+		if frame.step == 0 {
+			d.setPaletteColor(d.pickColor(frame.Lines))
+			// Placeholder images are used as they are, so they don't use up a background image
+			d.BackgroundImage = nil
+			if !IsPlaceHolderImageCommand(frame.Lines) {
+				d.BackgroundImage = d.pickBackgroundImage(frame.Options)
+			}
+			//let it use auto font size
+			d.SetFontSize(0)
+		}
 
-		fileName := LeftPad2Len(strconv.Itoa(index), "0", overallLenForPadding) + ".jpg"
-		if IsPlaceHolderImageCommand(snippet) {
-			d.bringInPlaceholderImageToItsRightPlace(snippet[0], fileName)
+		var img image.Image
+		fileName := fileNames[index][0]
+		if IsPlaceHolderImageCommand(frame.Lines) && d.FrameSequence {
+			img = d.loadPlaceholderImage(d.placeholderPath(frame.Lines[0]))
+			if img == nil {
+				// Every file name of the sequence is written, so that encoders don't stop at a gap
+				fmt.Printf("MISSING placeholder image %s, drawing the background instead\n", d.placeholderPath(frame.Lines[0]))
+				img = d.drawBackgroundImage()
+			}
+			d.writeFrames(img, fileNames[index])
+		} else if IsPlaceHolderImageCommand(frame.Lines) {
+			output := d.bringInPlaceholderImageToItsRightPlace(frame.Lines[0], fileName)
+			if len(transitions) > 0 || d.transitionFrameCount() > 0 {
+				img = d.loadPlaceholderImage(output)
+			}
 		} else {
-			d.drawSnippet(snippet, filepath.Join(d.OutputFolder, fileName))
+			img = d.drawSnippet(frame)
+			d.writeFrames(img, fileNames[index])
+		}
+
+		for i, transition := range transitions {
+			t := float64(i+1) / float64(len(transitions)+1)
+			writeImage(d.drawTransition(previous, img, t), filepath.Join(d.OutputFolder, transition))
+		}
+		transitions = transitions[:0]
+		previous = img
+
+		if frame.card != nil && frame.step == 0 {
+			cardRows = append(cardRows, cardRow{card: frame.card, questionFrame: fileName})
+		} else if frame.card != nil {
+			cardRows[len(cardRows)-1].answerFrame = fileName
 		}
 	}
+
+	if len(d.contrastReport) > 0 {
+		fmt.Printf("%d COLOR PAIRS below the contrast ratio of %.1f:1, see contrast.txt\n", len(d.contrastReport), d.MinContrast)
+		file, err := os.Create(filepath.Join(d.OutputFolder, "contrast.txt"))
+		if err != nil {
+			panic(err.Error())
+		}
+		defer file.Close()
+
+		if err = writeContrastReport(file, d.contrastReport, d.MinContrast); err != nil {
+			panic(err.Error())
+		}
+	}
+
+	if len(cardRows) > 0 {
+		file, err := os.Create(filepath.Join(d.OutputFolder, "flashcards.tsv"))
+		if err != nil {
+			panic(err.Error())
+		}
+		defer file.Close()
+
+		if err = writeCardsTSV(file, cardRows); err != nil {
+			panic(err.Error())
+		}
+	}
+}
+
+// writeFrames writes img under the first file name and links the other ones to it
+func (d *drawer) writeFrames(img image.Image, fileNames []string) {
+	first := filepath.Join(d.OutputFolder, fileNames[0])
+	writeImage(img, first)
+	for _, fileName := range fileNames[1:] {
+		linkFrame(first, filepath.Join(d.OutputFolder, fileName))
+	}
+}
+
+// linkFrame makes output a duplicate of the frame at path, without using more disk space when possible
+func linkFrame(path, output string) {
+	os.Remove(output)
+	if err := os.Link(path, output); err == nil {
+		return
+	}
+	if err := os.Symlink(filepath.Base(path), output); err == nil {
+		return
+	}
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err.Error())
+	}
+	if err = ioutil.WriteFile(output, src, 0644); err != nil {
+		panic(err.Error())
+	}
+}
+
+func writeImage(img image.Image, output string) {
+	file, err := os.Create(output)
+	if err != nil {
+		panic(err.Error())
+	}
+
+	defer file.Close()
+
+	if filepath.Ext(output) == ".png" {
+		err = png.Encode(file, img)
+	} else {
+		err = jpeg.Encode(file, img, &jpeg.Options{Quality: 100})
+	}
+	if err != nil {
+		panic(err.Error())
+	}
 }
 
 func (d *drawer) drawBackgroundImage() (*image.RGBA) {
-	var img *image.RGBA
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	draw.Draw(img, img.Bounds(), d.BackgroundColor, image.ZP, draw.Src)
+	if d.background != nil {
+		draw.Draw(img, img.Bounds(), d.patternedBackground(color.RGBAModel.Convert(d.BackgroundColor.C).(color.RGBA)), image.ZP, draw.Src)
+	}
 
 	if d.BackgroundImage != nil {
-		imgRect := image.Rectangle{image.Pt(0, 0), d.BackgroundImage.Bounds().Size()}
-		img = image.NewRGBA(imgRect)
-		draw.Draw(img, img.Bounds(), d.BackgroundImage, image.ZP, draw.Src)
-	} else {
-		img = image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
-		draw.Draw(img, img.Bounds(), d.BackgroundColor, image.ZP, draw.Src)
+		draw.Draw(img, img.Bounds(), d.fittedBackground(d.BackgroundImage), image.ZP, draw.Over)
 	}
 
 	return img
 }
 
-func setContextProperties(c *freetype.Context, d *drawer, img *image.RGBA) {
-	c.SetDPI(72)
-	c.SetFont(d.Font)
-	c.SetFontSize(d.FontSize)
-	c.SetClip(img.Bounds())
-	c.SetDst(img)
-	c.SetSrc(d.TextColor)
-	c.SetHinting(font.HintingNone)
+// spanLines splits the lines of a snippet into styled spans. Markup is not parsed in code.
+func spanLines(snippet Snippet) [][]span {
+	lines := make([][]span, 0, len(snippet.Lines))
+	for _, line := range snippet.Lines {
+		line = strings.Trim(line, " \t\n\r")
+		if line == "" {
+			continue
+		}
+		if snippet.Code {
+			lines = append(lines, plainSpans(line))
+		} else {
+			lines = append(lines, parseMarkup(line))
+		}
+	}
+	return lines
 }
 
-func (d *drawer) drawSnippet(lines []string, output string) {
-	var err error
+func (d *drawer) drawSnippet(f frame) *image.RGBA {
+	lines := f.lines()
 
 	//Calculate the minimum font fize considering all the text lines in the snippet
 	if d.autoFontSize {
-		d.FontSize = d.calcFontSizeForMultipleLines(lines)
+		d.FontSize = d.calcFontSizeForMultipleLines(lines, !f.Code)
 	}
 
+	align, verticalAlign := d.alignments(f.Options)
+	baselines, _ := d.layoutLines(lines, !f.Code, d.FontSize)
+	top := d.blockTop(baselines, d.FontSize, verticalAlign)
+
 	var img *image.RGBA = d.drawBackgroundImage()
-	
-	if d.Font != nil {
-		c := freetype.NewContext()
-		setContextProperties(c, d, img)
+	if d.BackgroundImage != nil {
+		// Text over images takes the color most readable on the area under it
+		bounds := d.textBounds(lines, baselines, top, align)
+		d.drawOverlay(img, bounds)
+		background := averageColor(img, bounds)
+		d.TextColor = image.NewUniform(d.checkContrast(readableTextColor(background), background, "background image"))
+	} else if d.background != nil {
+		textColor := color.RGBAModel.Convert(d.TextColor.C).(color.RGBA)
+		background := averageColor(img, d.textBounds(lines, baselines, top, align))
+		d.TextColor = image.NewUniform(d.checkContrast(textColor, background, "background pattern"))
+	}
 
-		gapFromLastLine := 0
-		textHeight := int(c.PointToFixed(d.FontSize) >> 6)
-		startingHeightPoint := (d.Height - len(lines) * textHeight - (len(lines) - 1) * 40) / 2
+	if d.Font != nil {
+		if f.Code {
+			d.drawCode(img, lines, baselines, top, align)
+			return img
+		}
 
-		for _, line := range lines {
-			line = strings.Trim(line, " \t\n\r")
-			if line == "" {
-				continue
+		text, rules := img, img
+		if d.TextStyle.hasStroke() || d.TextStyle.hasShadow() {
+			// Glyphs are drawn on a layer of their own, whose masks give the shape of their outline and shadow.
+			// Highlights go under the outline and the shadow, underlines and blank lines over them.
+			text, rules = image.NewRGBA(img.Bounds()), image.NewRGBA(img.Bounds())
+		}
+		for index, line := range lines {
+			if f.visible > 0 && index >= f.visible {
+				break
 			}
-			// line = line + "."
-			// pt := freetype.Pt((d.Width-textWidth)/2+d.TextPosHorizontal, (d.Height+textHeight)/2+d.TextPosVertical + gapFromLastLine)
-
-			// Use the below one for center alignment of text
-			textWidth := d.calcTextWidth(d.FontSize, line)
-			pt := freetype.Pt((d.Width-textWidth)/2+d.TextPosHorizontal, startingHeightPoint + gapFromLastLine)
 
-			// Use the below one for left alignment of text
-			// pt := freetype.Pt(d.TextPosHorizontal, startingHeightPoint + gapFromLastLine)
-
-			gapFromLastLine += textHeight + 40
-			_, err = c.DrawString(line, pt)
+			x, wordSpacing := d.lineStart(line, align, index == len(lines)-1)
+			pt := fixed.Point26_6{X: x, Y: top + baselines[index]}
+			d.drawHighlights(img, line, d.FontSize, pt, wordSpacing)
+			d.drawGlyphs(text, rules, line, d.FontSize, pt, wordSpacing)
+		}
+		if text != img {
+			d.drawTextEffects(img, text)
+			draw.Draw(img, img.Bounds(), rules, image.ZP, draw.Over)
+			draw.Draw(img, img.Bounds(), text, image.ZP, draw.Over)
 		}
 	}
 
-	file, err := os.Create(output)
-	if err != nil {
-		panic(err.Error())
-	}
-
-	defer file.Close()
-
-	if err = jpeg.Encode(file, img, &jpeg.Options{Quality: 100}); err != nil {
-		panic(err.Error())
-	}
+	return img
 }
 
-// SetBackgroundImage sets the specific background image
+// SetBackgroundImage sets the background image, or the background images when imagePath is a directory or a glob.
+// Only the first image is decoded here, the others are decoded when a snippet is first drawn on them.
 func (d *drawer) SetBackgroundImage(imagePath string) (err error) {
-	src, err := os.Open(imagePath)
+	paths, err := backgroundImagePaths(imagePath)
 	if err != nil {
 		return
 	}
-	defer src.Close()
-
-	img, _, err := image.Decode(src)
+	img, err := d.loadBackgroundImage(paths[0])
 	if err != nil {
 		return
 	}
+	d.BackgroundImagePaths = paths
 	d.BackgroundImage = img
 	return
 }
 
+// SetBackgroundOrder sets the order the background images are assigned to snippets in, round-robin by default
+func (d *drawer) SetBackgroundOrder(order ImageOrder) error {
+	switch order {
+	case "":
+		order = ImageRoundRobin
+	case ImageRoundRobin, ImageRandom:
+	default:
+		return fmt.Errorf("unknown background image order %q", order)
+	}
+	d.BackgroundOrder = order
+	return nil
+}
+
+// SetBackgroundFit sets how the background image is fitted to the canvas, cropped around its center by default.
+// A nil focal point keeps the center of the image in view.
+func (d *drawer) SetBackgroundFit(fit Fit, focalPoint *FocalPoint) error {
+	switch fit {
+	case "":
+		fit = FitCover
+	case FitCover, FitContain, FitStretch, FitTile, FitCenter:
+	default:
+		return fmt.Errorf("unknown background fit %q", fit)
+	}
+	d.BackgroundFit = fit
+	d.FocalPoint = FocalPoint{0.5, 0.5}
+	if focalPoint != nil {
+		d.FocalPoint = *focalPoint
+	}
+	return nil
+}
+
+// SetBackground sets the procedural background drawn instead of the flat background color,
+// unless the colors picked for a snippet have their own
+func (d *drawer) SetBackground(background Background) {
+	d.Background = background
+	// Renders of the previous background are keyed by the same pointer
+	d.patterns = nil
+}
+
 // SetColors sets the textColor and the backgroundColor
 func (d *drawer) SetColors(textColor, backgroundColor color.RGBA) {
 	r1, g1, b1, a1 := backgroundColor.RGBA()
 	r2, g2, b2, a2 := textColor.RGBA()
 	if r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2 {
-		color := PickColor()
-		d.TextColor = image.NewUniform(color.TextColor)
-		d.BackgroundColor = image.NewUniform(color.BackgroundColor)
+		d.setPaletteColor(d.pickColor(nil))
 		return
 	}
-	d.TextColor = image.NewUniform(textColor)
-	d.BackgroundColor = image.NewUniform(backgroundColor)
+	d.setColors(Color{backgroundColor, textColor}, nil, "SetColors")
+}
+
+// setPaletteColor sets the colors of the palette at index, with their gradient or pattern if they have one
+func (d *drawer) setPaletteColor(index int) {
+	var background *Background
+	if len(d.Palette) > 0 && index < len(d.PaletteBackgrounds) {
+		background = d.PaletteBackgrounds[index]
+	}
+	d.setColors(d.palette()[index], background, "palette")
+}
+
+// setColors sets the colors of c, its text color checked for contrast against its background,
+// and background, or the background of the drawer when nil
+func (d *drawer) setColors(c Color, background *Background, source string) {
+	d.background = background
+	c.TextColor = d.checkContrast(c.TextColor, c.BackgroundColor, source)
+	d.TextColor = image.NewUniform(c.TextColor)
+	d.BackgroundColor = image.NewUniform(c.BackgroundColor)
+	d.AccentColor = image.NewUniform(d.pickAccentColor(c))
+	if d.background == nil && d.Background.Kind != BackgroundSolid {
+		d.background = &d.Background
+	}
 }
 
 // SetColors sets the font
 func (d *drawer) SetFontPath(fontPath string) (err error) {
-	fontBytes, err := ioutil.ReadFile(fontPath)
+	f, err := loadFont(fontPath)
 	if err != nil {
 		return
 	}
-	f, err := freetype.ParseFont(fontBytes)
+	d.Font = f
+	return
+}
+
+// SetBoldFontPath sets the font of **bold** text
+func (d *drawer) SetBoldFontPath(fontPath string) (err error) {
+	f, err := loadFont(fontPath)
 	if err != nil {
 		return
 	}
-	d.Font = f
+	d.BoldFont = f
 	return
 }
 
+// SetItalicFontPath sets the font of *italic* text
+func (d *drawer) SetItalicFontPath(fontPath string) (err error) {
+	f, err := loadFont(fontPath)
+	if err != nil {
+		return
+	}
+	d.ItalicFont = f
+	return
+}
+
+// SetBoldItalicFontPath sets the font of ***bold italic*** text
+func (d *drawer) SetBoldItalicFontPath(fontPath string) (err error) {
+	f, err := loadFont(fontPath)
+	if err != nil {
+		return
+	}
+	d.BoldItalicFont = f
+	return
+}
+
+// SetCodeFontPath sets the font of code snippets
+func (d *drawer) SetCodeFontPath(fontPath string) (err error) {
+	f, err := loadFont(fontPath)
+	if err != nil {
+		return
+	}
+	d.CodeFont = f
+	return
+}
+
+// SetFallbackFontPaths sets the fonts, in order of preference, used for runes missing from the font.
+// The first one is also used as the font when the drawer has none.
+func (d *drawer) SetFallbackFontPaths(fontPaths ...string) (err error) {
+	fonts := make([]*truetype.Font, 0, len(fontPaths))
+	for _, fontPath := range fontPaths {
+		f, err := loadFont(fontPath)
+		if err != nil {
+			return err
+		}
+		fonts = append(fonts, f)
+	}
+	d.FallbackFonts = fonts
+	if d.Font == nil && len(fonts) > 0 {
+		d.Font = fonts[0]
+	}
+	return
+}
+
+// SetEmojiDir sets the directory of PNG emoji images, named by codepoint sequence like Twemoji
+func (d *drawer) SetEmojiDir(emojiDir string) (err error) {
+	e, err := newEmojiSet(emojiDir)
+	if err != nil {
+		return
+	}
+	d.emoji = e
+	return
+}
+
+// loadFont loads the font at fontPath, or the built-in font of that name
+func loadFont(fontPath string) (*truetype.Font, error) {
+	if fontBytes, ok := BuiltinFonts[fontPath]; ok {
+		return freetype.ParseFont(fontBytes)
+	}
+	fontBytes, err := ioutil.ReadFile(fontPath)
+	if err != nil {
+		return nil, err
+	}
+	return freetype.ParseFont(fontBytes)
+}
+
 // SetColors sets the fontSize
 func (d *drawer) SetFontSize(fontSize float64) {
 	if fontSize > 0 {
@@ -544,13 +1006,36 @@ func (d *drawer) SetFontSize(fontSize float64) {
 	d.autoFontSize = true
 }
 
-func (d *drawer) bringInPlaceholderImageToItsRightPlace(snippetLine string, fileName string) {
+func (d *drawer) bringInPlaceholderImageToItsRightPlace(snippetLine string, fileName string) (string) {
 	placeholderFilename := strings.Replace(snippetLine, "PLACEHOLDER_IMAGE ", "", -1)
 	fmt.Printf("PLACEHOLDER FILE NAME = %s\n", placeholderFilename)
 	if strings.HasSuffix(placeholderFilename, ".png") {
 		fileName = strings.Replace(fileName, ".jpg", ".png", -1)
 	}
-	os.Rename(filepath.Join(d.OutputFolder, placeholderFilename), filepath.Join(d.OutputFolder, fileName))
+	output := filepath.Join(d.OutputFolder, fileName)
+	os.Rename(d.placeholderPath(snippetLine), output)
+	return output
+}
+
+func (d *drawer) placeholderPath(snippetLine string) (string) {
+	return filepath.Join(d.OutputFolder, strings.Replace(snippetLine, "PLACEHOLDER_IMAGE ", "", -1))
+}
+
+// loadPlaceholderImage returns the placeholder image drawn on the canvas, or nil when it can't be decoded
+func (d *drawer) loadPlaceholderImage(path string) (image.Image) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil
+	}
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	return img
 }
 
 // SetFontPos sets the fontPos
@@ -584,41 +1069,244 @@ func (d *drawer) SetOutputFolder(outputFolder string) {
 	d.OutputFolder = outputFolder
 }
 
-func (d *drawer) calcFontSizeForSingleLine(text string) (fontSize float64) {
+// SetBlankWidth sets the width of vocabulary blanks, as a multiple of the font size
+func (d *drawer) SetBlankWidth(blankWidth float64) {
+	if blankWidth <= 0 {
+		d.BlankWidth = 4
+	} else {
+		d.BlankWidth = blankWidth
+	}
+}
+
+// SetRevealBlanks makes each snippet with answered blanks followed by a frame revealing the answers
+func (d *drawer) SetRevealBlanks(revealBlanks bool) {
+	d.RevealBlanks = revealBlanks
+}
+
+// SetFlashcards draws each "Q:" and "A:" pair of the notes as a question frame and an answer frame,
+// and exports the cards to flashcards.tsv in the output folder
+func (d *drawer) SetFlashcards(flashcards bool) {
+	d.Flashcards = flashcards
+}
+
+// SetBuildFrames draws multi-line text snippets line by line, each frame adding the next line
+func (d *drawer) SetBuildFrames(buildFrames bool) {
+	d.BuildFrames = buildFrames
+}
+
+// SetTransition sets the transition generated between snippets and its duration in seconds
+func (d *drawer) SetTransition(transition Transition, duration float64) error {
+	switch transition {
+	case TransitionNone, TransitionFade, TransitionPush, TransitionWipe:
+	default:
+		return fmt.Errorf("unknown transition %q", transition)
+	}
+	d.Transition = transition
+	if duration <= 0 {
+		d.TransitionDuration = 0.5
+	} else {
+		d.TransitionDuration = duration
+	}
+	return nil
+}
+
+// SetFrameRate sets the frames per second of the generated sequence
+func (d *drawer) SetFrameRate(frameRate float64) {
+	if frameRate <= 0 {
+		d.FrameRate = 30
+	} else {
+		d.FrameRate = frameRate
+	}
+}
+
+// SetFrameSequence writes each frame as many times as its snippet lasts at the frame rate, as a %06d.png sequence.
+// Snippets last duration seconds unless set otherwise by a DURATION directive.
+func (d *drawer) SetFrameSequence(frameSequence bool, duration float64) {
+	d.FrameSequence = frameSequence
+	if duration <= 0 {
+		d.Duration = 3
+	} else {
+		d.Duration = duration
+	}
+}
+
+// SetAlign sets the horizontal and vertical alignments of snippets, centered by default.
+// ALIGN and VALIGN directives override them for a single snippet.
+func (d *drawer) SetAlign(align Align, verticalAlign VerticalAlign) {
+	d.Align = align
+	if d.Align == "" {
+		d.Align = AlignCenter
+	}
+	d.VerticalAlign = verticalAlign
+	if d.VerticalAlign == "" {
+		d.VerticalAlign = VerticalAlignMiddle
+	}
+}
+
+// SetLineSpacing sets the height of lines and the space between the sentences of a snippet,
+// both as multiples of the font size. A negative paragraph spacing removes the space between sentences.
+func (d *drawer) SetLineSpacing(lineHeight, paragraphSpacing float64) {
+	if lineHeight <= 0 {
+		d.LineHeight = 1.5
+	} else {
+		d.LineHeight = lineHeight
+	}
+	if paragraphSpacing < 0 {
+		d.ParagraphSpacing = 0
+	} else if paragraphSpacing == 0 {
+		d.ParagraphSpacing = 0.5
+	} else {
+		d.ParagraphSpacing = paragraphSpacing
+	}
+}
+
+// SetMargins sets the space kept free on each side of the image
+func (d *drawer) SetMargins(margins Margins) {
+	d.Margins = margins
+}
+
+// SetCodePanel sets the look of code snippets. A panel with a background gets a padding of 0.6 and a radius of 0.3
+// times the font size unless set otherwise.
+func (d *drawer) SetCodePanel(panel CodePanel) {
+	if panel.Padding <= 0 {
+		panel.Padding = 0.6
+	}
+	if panel.Radius <= 0 {
+		panel.Radius = 0.3
+	}
+	d.CodePanel = panel
+}
+
+// SetOverlay sets the overlay drawn over background images to keep text readable,
+// in black at half opacity and with a blur radius of 8 pixels unless set otherwise
+func (d *drawer) SetOverlay(overlay Overlay) error {
+	switch overlay.Kind {
+	case OverlayNone, OverlayDim, OverlayScrim, OverlayBlur, OverlayBox:
+	default:
+		return fmt.Errorf("unknown overlay %q", overlay.Kind)
+	}
+	if overlay.Color.A == 0 {
+		overlay.Color = color.RGBA{0, 0, 0, 255}
+	}
+	if overlay.Opacity <= 0 {
+		overlay.Opacity = 0.5
+	}
+	if overlay.Radius <= 0 {
+		overlay.Radius = 8
+	}
+	d.Overlay = overlay
+	return nil
+}
+
+// SetTextStyle sets the outline and the shadow drawn under text, none by default.
+// Outlines and shadows without a color are black, shadows being half transparent by default.
+func (d *drawer) SetTextStyle(style TextStyle) {
+	if style.StrokeColor.A == 0 {
+		style.StrokeColor = color.RGBA{0, 0, 0, 255}
+	}
+	if style.ShadowColor.A == 0 {
+		style.ShadowColor = color.RGBA{0, 0, 0, 255}
+	}
+	if style.ShadowOpacity <= 0 {
+		style.ShadowOpacity = 0.5
+	}
+	d.TextStyle = style
+}
+
+// SetPalette sets the pairs of colors images are drawn with and the colors of highlights and revealed answers.
+// The built-in colors are used when palette is empty.
+func (d *drawer) SetPalette(palette []Color, accentColors []color.RGBA) {
+	d.Palette = palette
+	d.AccentColors = accentColors
+}
+
+// SetPaletteBackgrounds sets the gradients and patterns drawn instead of the flat background color
+// of the palette colors at the same index, nil for the colors drawn flat
+func (d *drawer) SetPaletteBackgrounds(backgrounds []*Background) {
+	d.PaletteBackgrounds = backgrounds
+}
+
+// SetColorStrategy sets how the colors of each snippet are picked, random colors by default.
+// Random colors are seeded from seed, or from the hash of the notes when it is 0.
+func (d *drawer) SetColorStrategy(strategy ColorStrategy, seed int64) error {
+	switch strategy {
+	case "":
+		strategy = ColorRandom
+	case ColorRandom, ColorRoundRobin, ColorFixed, ColorDistinct, ColorHash:
+	default:
+		return fmt.Errorf("unknown color strategy %q", strategy)
+	}
+	d.ColorStrategy = strategy
+	d.Seed = seed
+	d.rand = nil
+	d.imageRand = nil
+	return nil
+}
+
+// SetMinColorDistance sets the CIEDE2000 difference kept between consecutive backgrounds by the distinct strategy,
+// 15 by default
+func (d *drawer) SetMinColorDistance(distance float64) {
+	if distance <= 0 {
+		d.MinColorDistance = 15
+	} else {
+		d.MinColorDistance = distance
+	}
+}
+
+// SetMinContrast sets the WCAG contrast ratio text must have with its background, 4.5 by default.
+// Text below it is switched to a light or dark color and reported in contrast.txt. A negative ratio disables the check.
+func (d *drawer) SetMinContrast(minContrast float64) {
+	if minContrast == 0 {
+		d.MinContrast = 4.5
+	} else {
+		d.MinContrast = minContrast
+	}
+}
+
+// SetSafeArea keeps text out of the parts of the image covered by the interface of a platform,
+// given by the name of a preset of SafeAreas. An empty name clears the safe area.
+func (d *drawer) SetSafeArea(name string) error {
+	safeArea, ok := SafeAreas[name]
+	if !ok && name != "" {
+		return fmt.Errorf("unknown safe area %q", name)
+	}
+	d.SafeArea = safeArea
+	return nil
+}
+
+// fontSizes are the sizes tried by automatic font sizing, from the largest to the smallest.
+// A snippet is drawn at the largest size its lines fit the text area at.
+var fontSizes = []float64{128, 64, 48, 32, 24, 18, 16, 14, 12}
+
+func (d *drawer) calcFontSizeForSingleLine(line []span) (fontSize float64) {
 	const padding = 4
-	fontSizes := []float64{128, 64, 48, 32, 24, 18, 16, 14, 12}
 	for _, fontSize = range fontSizes {
-		textWidth := d.calcTextWidth(fontSize, text)
-		if textWidth < d.Width {
+		textWidth := d.calcTextWidth(fontSize, line)
+		if textWidth < d.textArea().Dx() {
 			return
 		}
 	}
 	return
 }
 
-func (d *drawer) calcFontSizeForMultipleLines(lines []string) (float64) {
+func (d *drawer) calcFontSizeForMultipleLines(lines [][]span, paragraphs bool) (float64) {
 	var minFontSize float64 = 10000
 	for _, line := range lines {
 		minFontSize = math.Min(minFontSize, d.calcFontSizeForSingleLine(line))
 	}
-	return minFontSize
+	// The lines must also fit in the height of the text area
+	for _, fontSize := range fontSizes {
+		if fontSize <= minFontSize && d.blockHeight(lines, paragraphs, fontSize) <= d.textArea().Dy() {
+			return fontSize
+		}
+	}
+	return math.Min(minFontSize, fontSizes[len(fontSizes)-1])
 }
 
-func (d *drawer) calcTextWidth(fontSize float64, text string) (textWidth int) {
-	var face font.Face
-	if d.Font != nil {
-		opts := truetype.Options{}
-		opts.Size = fontSize
-		face = truetype.NewFace(d.Font, &opts)
-	} else {
-		face = basicfont.Face7x13
+func (d *drawer) calcTextWidth(fontSize float64, line []span) (textWidth int) {
+	width := d.measureSpans(line, fontSize)
+	if len(line) > 0 && line[0].code {
+		width += 2 * d.codePadding(fontSize)
 	}
-	for _, x := range text {
-		awidth, ok := face.GlyphAdvance(rune(x))
-		if ok != true {
-			return
-		}
-		textWidth += int(float64(awidth) / 64)
-	}
-	return
+	return width.Ceil()
 }
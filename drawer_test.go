@@ -1,30 +1,111 @@
 package text2img
 
 import (
-	"image/jpeg"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
 )
 
 func TestDraw(t *testing.T) {
 	path := "fonts/mplus-1c-bold.ttf"
+	output, err := ioutil.TempDir("", "text2img")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(output)
+
 	d, err := NewDrawer(Params{
-		FontPath: path,
+		FontPath:     path,
+		OutputFolder: output,
 	})
 	if err != nil {
 		t.Fatal(err.Error())
 	}
 
-	img, err := d.Draw("text2img generates the image from a text")
-	if err != nil {
+	d.Draw("text2img generates the image from a text")
+	if _, err = os.Stat(filepath.Join(output, "0.jpg")); err != nil {
 		t.Fatal(err.Error())
 	}
-	file, err := os.Create("test.jpg")
+}
+
+// largeNotes returns notes mixing single lines, text snippets and code snippets
+func largeNotes(sections int) string {
+	section := `Go is an open source programming language that makes it easy to build simple, reliable, and efficient software.
+[[[[[[
+A goroutine is a lightweight thread managed by the Go runtime.
+Channels are a typed conduit through which you can send and receive values with the channel operator, and by default sends and receives block until the other side is ready, which allows goroutines to synchronize without explicit locks or condition variables.
+]]]]]]
+{{{{{{
+ch := make(chan int)
+go func() { ch <- 42 }()
+fmt.Println(<-ch)
+}}}}}}
+The zero value of a slice is nil. A nil slice has a length and capacity of 0 and has no underlying array.
+`
+	return strings.Repeat(section, sections)
+}
+
+// discardStdout silences the progress output of Snippets while benchmarking
+func discardStdout(b *testing.B) func() {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
 	if err != nil {
-		t.Fatal(err.Error())
+		b.Fatal(err.Error())
 	}
-	defer file.Close()
-	if err = jpeg.Encode(file, img, &jpeg.Options{Quality: 100}); err != nil {
-		t.Fatal(err.Error())
+	os.Stdout = devNull
+	return func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}
+}
+
+func newBenchmarkDrawer(b *testing.B, output string) *drawer {
+	fontPath := filepath.Join(output, "goregular.ttf")
+	if err := ioutil.WriteFile(fontPath, goregular.TTF, 0644); err != nil {
+		b.Fatal(err.Error())
+	}
+	d, err := NewDrawer(Params{
+		FontPath:     fontPath,
+		OutputFolder: output,
+	})
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	return d.(*drawer)
+}
+
+func BenchmarkSnippets(b *testing.B) {
+	output, err := ioutil.TempDir("", "text2img")
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	defer os.RemoveAll(output)
+	defer discardStdout(b)()
+
+	d := newBenchmarkDrawer(b, output)
+	notes := largeNotes(200)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Snippets(notes)
+	}
+}
+
+func BenchmarkDraw(b *testing.B) {
+	output, err := ioutil.TempDir("", "text2img")
+	if err != nil {
+		b.Fatal(err.Error())
+	}
+	defer os.RemoveAll(output)
+	defer discardStdout(b)()
+
+	d := newBenchmarkDrawer(b, output)
+	notes := largeNotes(10)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Draw(notes)
 	}
 }
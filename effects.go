@@ -0,0 +1,113 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// TextStyle is the outline and the drop shadow drawn under text to keep it readable over photos and busy backgrounds
+type TextStyle struct {
+	// StrokeWidth is the width of the outline around glyphs in pixels, no outline being drawn when 0
+	StrokeWidth float64
+	// StrokeColor is the color of the outline, black when transparent
+	StrokeColor color.RGBA
+	// ShadowOffset is the offset of the shadow from the text in pixels.
+	// No shadow is drawn when both ShadowOffset and ShadowBlur are 0.
+	ShadowOffset image.Point
+	// ShadowBlur is the radius of the blur of the shadow in pixels
+	ShadowBlur float64
+	// ShadowColor is the color of the shadow, black when transparent, and ShadowOpacity its opacity between 0 and 1
+	ShadowColor   color.RGBA
+	ShadowOpacity float64
+}
+
+func (s TextStyle) hasStroke() bool {
+	return s.StrokeWidth > 0
+}
+
+func (s TextStyle) hasShadow() bool {
+	return s.ShadowOffset != image.ZP || s.ShadowBlur > 0
+}
+
+// drawTextEffects draws the shadow and the outline of the text drawn on layer onto dst, the text being drawn over them.
+// Their shape is given by the glyph masks, the alpha channel of layer.
+func (d *drawer) drawTextEffects(dst, layer *image.RGBA) {
+	mask := image.NewAlpha(layer.Bounds())
+	for i := range mask.Pix {
+		mask.Pix[i] = layer.Pix[i*4+3]
+	}
+	if d.TextStyle.hasStroke() {
+		mask = dilate(mask, d.TextStyle.StrokeWidth)
+	}
+
+	if d.TextStyle.hasShadow() {
+		c, opacity := d.TextStyle.ShadowColor, d.TextStyle.ShadowOpacity
+		shadowColor := color.RGBA{
+			uint8(float64(c.R) * opacity),
+			uint8(float64(c.G) * opacity),
+			uint8(float64(c.B) * opacity),
+			uint8(255 * opacity),
+		}
+		shadow := image.NewRGBA(layer.Bounds())
+		draw.DrawMask(shadow, shadow.Bounds(), image.NewUniform(shadowColor), image.ZP, mask, mask.Rect.Min, draw.Over)
+		blur(shadow, d.TextStyle.ShadowBlur)
+		draw.Draw(dst, shadow.Bounds().Add(d.TextStyle.ShadowOffset), shadow, shadow.Rect.Min, draw.Over)
+	}
+	if d.TextStyle.hasStroke() {
+		draw.DrawMask(dst, mask.Bounds(), image.NewUniform(d.TextStyle.StrokeColor), image.ZP, mask, mask.Rect.Min, draw.Over)
+	}
+}
+
+// dilate returns mask grown by width pixels in every direction, with antialiased round edges
+func dilate(mask *image.Alpha, width float64) *image.Alpha {
+	// Weights of the pixels of a disk around a pixel, grown by width from its edges,
+	// partially covered pixels on the edge of the disk getting partial weights
+	type offset struct {
+		x, y   int
+		weight float64
+	}
+	reach := int(math.Ceil(width + 1))
+	disk := make([]offset, 0)
+	for y := -reach; y <= reach; y++ {
+		for x := -reach; x <= reach; x++ {
+			if weight := clamp(width + 1 - math.Hypot(float64(x), float64(y))); weight > 0 {
+				disk = append(disk, offset{x, y, weight})
+			}
+		}
+	}
+
+	rect := mask.Bounds()
+	dilated := image.NewAlpha(rect)
+	at := func(x, y int) uint8 {
+		if !(image.Point{x, y}.In(rect)) {
+			return 0
+		}
+		return mask.Pix[mask.PixOffset(x, y)]
+	}
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			a := at(x, y)
+			if a == 0 {
+				continue
+			}
+			// The disks of the pixels inside glyphs are covered by the disks of the pixels on their edges
+			if a == 255 && at(x-1, y) == 255 && at(x+1, y) == 255 && at(x, y-1) == 255 && at(x, y+1) == 255 {
+				dilated.Pix[dilated.PixOffset(x, y)] = 255
+				continue
+			}
+			for _, o := range disk {
+				p := image.Pt(x+o.x, y+o.y)
+				if !p.In(rect) {
+					continue
+				}
+				i := dilated.PixOffset(p.X, p.Y)
+				if v := uint8(math.Round(float64(a) * o.weight)); v > dilated.Pix[i] {
+					dilated.Pix[i] = v
+				}
+			}
+		}
+	}
+	return dilated
+}
@@ -0,0 +1,90 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestDilate(t *testing.T) {
+	mask := image.NewAlpha(image.Rect(0, 0, 40, 40))
+	draw.Draw(mask, image.Rect(15, 15, 25, 25), image.Opaque, image.ZP, draw.Src)
+	dilated := dilate(mask, 3)
+
+	for _, p := range []image.Point{{20, 20}, {12, 20}, {27, 20}, {20, 12}, {20, 27}} {
+		if a := dilated.AlphaAt(p.X, p.Y).A; a != 255 {
+			t.Errorf("dilated mask must cover %v, got %v", p, a)
+		}
+	}
+	for _, p := range []image.Point{{11, 20}, {28, 20}, {12, 12}, {0, 0}} {
+		if a := dilated.AlphaAt(p.X, p.Y).A; a != 0 {
+			t.Errorf("dilated mask must not reach %v, got %v", p, a)
+		}
+	}
+	if a := dilate(mask, 2.5).AlphaAt(12, 20).A; a == 0 || a == 255 {
+		t.Errorf("dilated mask must be antialiased on its edges, got %v", a)
+	}
+}
+
+func TestDrawTextEffects(t *testing.T) {
+	white, red := color.RGBA{255, 255, 255, 255}, color.RGBA{255, 0, 0, 255}
+	// A layer with a 10x10 white square as text
+	layer := image.NewRGBA(image.Rect(0, 0, 60, 60))
+	draw.Draw(layer, image.Rect(20, 20, 30, 30), image.NewUniform(white), image.ZP, draw.Src)
+
+	d := &drawer{}
+	d.SetTextStyle(TextStyle{StrokeWidth: 2, StrokeColor: red})
+	dst := image.NewRGBA(layer.Bounds())
+	d.drawTextEffects(dst, layer)
+	if c := dst.RGBAAt(19, 25); c != red {
+		t.Errorf("outline must surround the text, got %v", c)
+	}
+	if c := dst.RGBAAt(35, 25); c.A != 0 {
+		t.Errorf("outline must not go further than its width, got %v", c)
+	}
+
+	d.SetTextStyle(TextStyle{ShadowOffset: image.Pt(10, 10), ShadowOpacity: 1})
+	dst = image.NewRGBA(layer.Bounds())
+	d.drawTextEffects(dst, layer)
+	if c := dst.RGBAAt(35, 35); c != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("shadow must be drawn at its offset, got %v", c)
+	}
+	if c := dst.RGBAAt(25, 25); c.A != 0 {
+		t.Errorf("shadow must not be drawn under the text without an offset, got %v", c)
+	}
+
+	d.SetTextStyle(TextStyle{ShadowOffset: image.Pt(10, 10), ShadowBlur: 6})
+	dst = image.NewRGBA(layer.Bounds())
+	d.drawTextEffects(dst, layer)
+	if inside, edge := dst.RGBAAt(35, 35).A, dst.RGBAAt(41, 35).A; inside == 0 || edge == 0 || edge >= inside {
+		t.Errorf("blurred shadow must fade out on its edges, got %v and %v", inside, edge)
+	}
+}
+
+func TestTextEffectsFollowGlyphsOnly(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	d := newTestDrawer(t)
+	d.FontSize = 40
+	d.SetColors(color.RGBA{255, 255, 255, 255}, color.RGBA{0x28, 0x36, 0x55, 255})
+	d.SetTextStyle(TextStyle{StrokeWidth: 3, StrokeColor: red})
+	outline := func(line string) (count int) {
+		img := d.drawSnippet(frame{Snippet: Snippet{Lines: []string{line}}})
+		for i := 0; i < len(img.Pix); i += 4 {
+			if img.Pix[i] == 255 && img.Pix[i+1] == 0 && img.Pix[i+2] == 0 {
+				count++
+			}
+		}
+		return
+	}
+
+	if count := outline("..."); count != 0 {
+		t.Errorf("blank lines must not be outlined, got %d outline pixels", count)
+	}
+	if plain, highlighted := outline("Outlined words"), outline("==Outlined words=="); plain == 0 || highlighted != plain {
+		t.Errorf("highlight boxes must not be outlined, got %d outline pixels instead of %d", highlighted, plain)
+	}
+	if plain, underlined := outline("Outlined words"), outline("__Outlined words__"); underlined != plain {
+		t.Errorf("underlines must not be outlined, got %d outline pixels instead of %d", underlined, plain)
+	}
+}
@@ -0,0 +1,140 @@
+package text2img
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	zeroWidthJoiner   = '\u200d'
+	textPresentation  = '\ufe0e'
+	emojiPresentation = '\ufe0f'
+)
+
+// emojiSet holds color emoji images stored as PNG files named by their codepoint sequence,
+// Twemoji style: "1f600.png", "1f44b-1f3fd.png" or "1f468-200d-1f469-200d-1f467.png".
+// Hex digits may be uppercase, like "1F600.png".
+type emojiSet struct {
+	dir string
+	// files maps the lowercase names of the emoji to their file names
+	files   map[string]string
+	starts  map[rune]bool
+	longest int
+	images  map[string]image.Image
+	scaled  map[emojiSize]image.Image
+}
+
+type emojiSize struct {
+	name string
+	size int
+}
+
+// newEmojiSet indexes the PNG files in dir. Images are decoded on first use.
+func newEmojiSet(dir string) (*emojiSet, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	e := &emojiSet{
+		dir:    dir,
+		files:  make(map[string]string),
+		starts: make(map[rune]bool),
+		images: make(map[string]image.Image),
+		scaled: make(map[emojiSize]image.Image),
+	}
+	for _, file := range files {
+		name := strings.ToLower(file.Name())
+		if file.IsDir() || filepath.Ext(name) != ".png" {
+			continue
+		}
+		name = strings.TrimSuffix(name, ".png")
+		codepoints := strings.Split(name, "-")
+		var first rune
+		if _, err := fmt.Sscanf(codepoints[0], "%x", &first); err != nil {
+			continue
+		}
+		e.files[name] = file.Name()
+		e.starts[first] = true
+		if len(codepoints) > e.longest {
+			e.longest = len(codepoints)
+		}
+	}
+	return e, nil
+}
+
+// emojiName returns the Twemoji file name of a codepoint sequence, optionally without variation selectors
+func emojiName(runes []rune, withVariationSelector bool) string {
+	codepoints := make([]string, 0, len(runes))
+	for _, r := range runes {
+		if r == emojiPresentation && !withVariationSelector {
+			continue
+		}
+		codepoints = append(codepoints, fmt.Sprintf("%x", r))
+	}
+	return strings.Join(codepoints, "-")
+}
+
+// match returns the name of the longest emoji sequence starting at runes[i] and the number of runes it spans.
+// ZWJ sequences and skin tone modifiers without an image of their own fall back to their components.
+func (e *emojiSet) match(runes []rune, i int) (name string, n int) {
+	if e == nil || !e.starts[runes[i]] {
+		return "", 0
+	}
+	n = e.longest
+	if n > len(runes)-i {
+		n = len(runes) - i
+	}
+	for ; n > 0; n-- {
+		seq := runes[i : i+n]
+		if name = emojiName(seq, true); e.files[name] != "" {
+			break
+		}
+		if name = emojiName(seq, false); e.files[name] != "" {
+			break
+		}
+	}
+	if n == 0 {
+		return "", 0
+	}
+	// A trailing variation selector belongs to the emoji even when the file name omits it
+	if i+n < len(runes) && runes[i+n] == emojiPresentation {
+		n++
+	}
+	return
+}
+
+// image returns the emoji scaled to a size x size square
+func (e *emojiSet) image(name string, size int) (image.Image, error) {
+	key := emojiSize{name, size}
+	if img, ok := e.scaled[key]; ok {
+		return img, nil
+	}
+	src, ok := e.images[name]
+	if !ok {
+		file, err := os.Open(filepath.Join(e.dir, e.files[name]))
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		if src, err = png.Decode(file); err != nil {
+			return nil, err
+		}
+		e.images[name] = src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), xdraw.Over, nil)
+	e.scaled[key] = dst
+	return dst, nil
+}
+
+// isInvisible reports whether r only modifies its neighbours and must not be drawn on its own
+func isInvisible(r rune) bool {
+	return r == zeroWidthJoiner || r == textPresentation || r == emojiPresentation
+}
@@ -0,0 +1,82 @@
+package text2img
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestEmojiSet(t *testing.T, names ...string) (*emojiSet, func()) {
+	dir, err := ioutil.TempDir("", "emoji")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	for _, name := range names {
+		file, err := os.Create(filepath.Join(dir, name+".png"))
+		if err != nil {
+			t.Fatal(err.Error())
+		}
+		png.Encode(file, image.NewRGBA(image.Rect(0, 0, 72, 72)))
+		file.Close()
+	}
+	e, err := newEmojiSet(dir)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	return e, func() { os.RemoveAll(dir) }
+}
+
+func TestEmojiMatch(t *testing.T) {
+	e, cleanup := newTestEmojiSet(t, "1f44b", "1f44b-1f3fd", "1f468", "1f469", "1f468-200d-1f469-200d-1f467", "2764")
+	defer cleanup()
+
+	tests := []struct {
+		text string
+		name string
+		n    int
+	}{
+		{"👋 hello", "1f44b", 1},
+		{"👋🏽 hello", "1f44b-1f3fd", 2},
+		{"👨‍👩‍👧", "1f468-200d-1f469-200d-1f467", 5},
+		{"👨‍👩", "1f468", 1},
+		{"❤️", "2764", 2},
+		{"hello", "", 0},
+	}
+	for _, test := range tests {
+		name, n := e.match([]rune(test.text), 0)
+		if name != test.name || n != test.n {
+			t.Errorf("%q must match %q over %d runes, got %q over %d", test.text, test.name, test.n, name, n)
+		}
+	}
+}
+
+func TestEmojiImageIsScaledToFontSize(t *testing.T) {
+	e, cleanup := newTestEmojiSet(t, "1f600")
+	defer cleanup()
+
+	img, err := e.image("1f600", 48)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if size := img.Bounds().Size(); size.X != 48 || size.Y != 48 {
+		t.Errorf("emoji must be 48x48, got %v", size)
+	}
+}
+
+func TestEmojiUppercaseFileNames(t *testing.T) {
+	e, cleanup := newTestEmojiSet(t, "1F600", "1F44B-1F3FD")
+	defer cleanup()
+
+	name, n := e.match([]rune("👋🏽"), 0)
+	if name != "1f44b-1f3fd" || n != 2 {
+		t.Errorf("uppercase file names must match, got %q over %d runes", name, n)
+	}
+	for _, name := range []string{"1f600", "1f44b-1f3fd"} {
+		if _, err := e.image(name, 48); err != nil {
+			t.Errorf("%s must be opened from its uppercase file name, got %s", name, err.Error())
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package text2img
+
+import (
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// faceKey identifies a face by its font and size
+type faceKey struct {
+	font *truetype.Font
+	size float64
+}
+
+// advanceKey identifies the advance of a rune in a face
+type advanceKey struct {
+	faceKey
+	r rune
+}
+
+type advance struct {
+	width fixed.Int26_6
+	ok    bool
+}
+
+// faceCache keeps one face per font and size, and the advances measured with them.
+// Auto font sizing measures every line at every candidate size, so creating
+// a new face each time dominates the render time of long notes.
+type faceCache struct {
+	faces    map[faceKey]font.Face
+	advances map[advanceKey]advance
+}
+
+// face returns the face of f at the given size. A nil font falls back to basicfont.
+func (fc *faceCache) face(f *truetype.Font, size float64) font.Face {
+	if f == nil {
+		return basicfont.Face7x13
+	}
+	key := faceKey{f, size}
+	if face, ok := fc.faces[key]; ok {
+		return face
+	}
+	if fc.faces == nil {
+		fc.faces = make(map[faceKey]font.Face)
+	}
+	face := truetype.NewFace(f, &truetype.Options{Size: size})
+	fc.faces[key] = face
+	return face
+}
+
+// glyphAdvance returns the memoized advance width of r in the face of f at the given size.
+func (fc *faceCache) glyphAdvance(f *truetype.Font, size float64, r rune) (fixed.Int26_6, bool) {
+	key := advanceKey{faceKey{f, size}, r}
+	if a, ok := fc.advances[key]; ok {
+		return a.width, a.ok
+	}
+	if fc.advances == nil {
+		fc.advances = make(map[advanceKey]advance)
+	}
+	width, ok := fc.face(f, size).GlyphAdvance(r)
+	fc.advances[key] = advance{width, ok}
+	return width, ok
+}
@@ -0,0 +1,84 @@
+package text2img
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	questionPrefix = "Q:"
+	answerPrefix   = "A:"
+)
+
+// Card is a flashcard written in the notes as a "Q:" line followed by an "A:" line
+type Card struct {
+	Question []string
+	Answer   []string
+}
+
+// cardAt returns the card starting at snippets[i] and the number of snippets it spans.
+// The question and the answer are either consecutive snippets or lines of the same text snippet.
+func cardAt(snippets []Snippet, i int) (card Card, n int, ok bool) {
+	snippet := snippets[i]
+	if snippet.Code || len(snippet.Lines) == 0 || !strings.HasPrefix(snippet.Lines[0], questionPrefix) {
+		return
+	}
+	for j, line := range snippet.Lines {
+		if j > 0 && strings.HasPrefix(line, answerPrefix) {
+			return Card{trimCardPrefix(snippet.Lines[:j]), trimCardPrefix(snippet.Lines[j:])}, 1, true
+		}
+	}
+	if i+1 < len(snippets) {
+		next := snippets[i+1]
+		if !next.Code && len(next.Lines) > 0 && strings.HasPrefix(next.Lines[0], answerPrefix) {
+			return Card{trimCardPrefix(snippet.Lines), trimCardPrefix(next.Lines)}, 2, true
+		}
+	}
+	return
+}
+
+// trimCardPrefix removes the "Q:" or "A:" prefix of the first line
+func trimCardPrefix(lines []string) []string {
+	trimmed := append([]string{}, lines...)
+	first := strings.TrimPrefix(strings.TrimPrefix(trimmed[0], questionPrefix), answerPrefix)
+	trimmed[0] = strings.Trim(first, " \t\n\r")
+	return trimmed
+}
+
+// cardRow is a card along with the file names of its question and answer frames
+type cardRow struct {
+	card          *Card
+	questionFrame string
+	answerFrame   string
+}
+
+// writeCardsTSV writes cards as tab-separated question, answer and frame image columns,
+// which spaced-repetition apps such as Anki import as notes
+func writeCardsTSV(w io.Writer, rows []cardRow) error {
+	for _, row := range rows {
+		_, err := fmt.Fprintf(w, "%s\t%s\t<img src=\"%s\">\t<img src=\"%s\">\n",
+			tsvField(row.card.Question), tsvField(row.card.Answer), row.questionFrame, row.answerFrame)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tsvField joins lines as the plain text of a TSV field, without markup, tabs or newlines
+func tsvField(lines []string) string {
+	texts := make([]string, 0, len(lines))
+	for _, line := range spanLines(Snippet{Lines: lines}) {
+		text := ""
+		for _, s := range line {
+			if s.blank {
+				text += "..."
+			} else {
+				text += s.text
+			}
+		}
+		texts = append(texts, strings.Replace(text, "\t", " ", -1))
+	}
+	return strings.Join(texts, "<br>")
+}
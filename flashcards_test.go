@@ -0,0 +1,49 @@
+package text2img
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestFlashcardFrames(t *testing.T) {
+	snippets := []Snippet{
+		{Lines: []string{"Q: What is a goroutine?"}},
+		{Lines: []string{"A: A lightweight thread.", "It is managed by the Go runtime."}},
+		{Lines: []string{"Q: What does **defer** do?", "A: It delays a call until the function returns."}},
+		{Lines: []string{"Just a slide."}},
+	}
+
+	d := &drawer{}
+	d.SetFlashcards(true)
+	frames := d.frames(snippets)
+	if len(frames) != 5 {
+		t.Fatalf("each card must be drawn as a question and an answer frame, got %d frames", len(frames))
+	}
+
+	if !reflect.DeepEqual(frames[0].Lines, []string{"What is a goroutine?"}) {
+		t.Errorf("question frame must show the question, got %q", frames[0].Lines)
+	}
+	expected := []string{"What is a goroutine?", "A lightweight thread.", "It is managed by the Go runtime."}
+	if !reflect.DeepEqual(frames[1].Lines, expected) || frames[1].step != 1 {
+		t.Errorf("answer frame must show the question and the answer, got %q", frames[1].Lines)
+	}
+	if frames[2].card == nil || frames[3].card != frames[2].card || frames[4].card != nil {
+		t.Errorf("question and answer lines of one snippet must make one card")
+	}
+}
+
+func TestWriteCardsTSV(t *testing.T) {
+	card := Card{
+		Question: []string{"What does **defer** do?"},
+		Answer:   []string{"It delays a call", "until the function returns."},
+	}
+	var buf bytes.Buffer
+	if err := writeCardsTSV(&buf, []cardRow{{&card, "08.jpg", "09.jpg"}}); err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := "What does defer do?\tIt delays a call<br>until the function returns.\t<img src=\"08.jpg\">\t<img src=\"09.jpg\">\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
@@ -0,0 +1,143 @@
+package text2img
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// frame is one image drawn for a snippet. A snippet is drawn as several frames
+// when its content is revealed step by step.
+type frame struct {
+	Snippet
+	// step is the index of the frame among the frames of its snippet
+	step int
+	// reveal shows the answers of the blanks
+	reveal bool
+	// visible is the number of lines drawn, all of them when 0.
+	// Hidden lines still take their place so that the layout is the same in every frame.
+	visible int
+	// card is the flashcard whose question or answer the frame shows
+	card *Card
+	// transition is the index, from 1, of a frame generated between the previous snippet and the next one
+	transition int
+}
+
+// frames expands snippets into the frames drawn for them, in order
+func (d *drawer) frames(snippets []Snippet) []frame {
+	frames := make([]frame, 0, len(snippets))
+	for i := 0; i < len(snippets); i++ {
+		snippet := snippets[i]
+		if d.Flashcards {
+			if card, n, ok := cardAt(snippets, i); ok {
+				question := Snippet{Lines: card.Question}
+				answer := Snippet{Lines: append(append([]string{}, card.Question...), card.Answer...)}
+				frames = append(frames, frame{Snippet: question, card: &card}, frame{Snippet: answer, step: 1, card: &card})
+				i += n - 1
+				continue
+			}
+		}
+
+		step := 0
+		if d.BuildFrames && !snippet.Code && len(snippet.Lines) > 1 {
+			for ; step < len(snippet.Lines)-1; step++ {
+				frames = append(frames, frame{Snippet: snippet, step: step, visible: step + 1})
+			}
+		}
+		frames = append(frames, frame{Snippet: snippet, step: step})
+		if d.RevealBlanks && hasAnswers(snippet) {
+			frames = append(frames, frame{Snippet: snippet, step: step + 1, reveal: true})
+		}
+	}
+	return d.withTransitions(frames)
+}
+
+// withTransitions inserts transition frames before the first frame of each snippet but the first one
+func (d *drawer) withTransitions(frames []frame) []frame {
+	count := d.transitionFrameCount()
+	if count == 0 {
+		return frames
+	}
+	withTransitions := make([]frame, 0, len(frames))
+	drawn := false
+	for _, f := range frames {
+		if f.step == 0 && len(f.Lines) > 0 {
+			for transition := 1; drawn && transition <= count; transition++ {
+				withTransitions = append(withTransitions, frame{transition: transition})
+			}
+			drawn = true
+		}
+		withTransitions = append(withTransitions, f)
+	}
+	return withTransitions
+}
+
+// lines returns the styled lines drawn for the frame
+func (f frame) lines() [][]span {
+	lines := spanLines(f.Snippet)
+	for _, line := range lines {
+		for i := range line {
+			line[i].reveal = f.reveal
+		}
+	}
+	return lines
+}
+
+// hasAnswers reports whether a snippet has blanks with an answer to reveal
+func hasAnswers(snippet Snippet) bool {
+	for _, line := range spanLines(snippet) {
+		for _, s := range line {
+			if s.blank && s.answer != "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fileNames returns the file names of each frame. The frames of a snippet in a frame sequence span as many files
+// as the snippet lasts, while a frame of a slide deck is one image numbered by its index.
+func (d *drawer) fileNames(frames []frame) [][]string {
+	fileNames := make([][]string, len(frames))
+	if !d.FrameSequence {
+		overallLenForPadding := len(strconv.Itoa(len(frames) - 1))
+		for index := range frames {
+			fileNames[index] = []string{LeftPad2Len(strconv.Itoa(index), "0", overallLenForPadding) + ".jpg"}
+		}
+		return fileNames
+	}
+
+	number := 0
+	total, steps := 0, 1
+	for index, f := range frames {
+		count := 1
+		if f.transition == 0 && len(f.Lines) == 0 {
+			count = 0
+		} else if f.transition == 0 {
+			if f.step == 0 {
+				duration := d.Duration
+				if f.Options.Duration > 0 {
+					duration = f.Options.Duration
+				}
+				total = int(math.Max(1, math.Round(duration*d.FrameRate)))
+				steps = snippetSteps(frames[index:])
+			}
+			// The duration of a snippet is split between the frames revealing it step by step
+			count = int(math.Max(1, float64(total*(f.step+1)/steps-total*f.step/steps)))
+		}
+		for i := 0; i < count; i++ {
+			fileNames[index] = append(fileNames[index], fmt.Sprintf("%06d.png", number))
+			number++
+		}
+	}
+	return fileNames
+}
+
+// snippetSteps returns the number of frames of the snippet whose first frame starts frames
+func snippetSteps(frames []frame) int {
+	steps := 1
+	for steps < len(frames) && frames[steps].transition == 0 && frames[steps].step > 0 {
+		steps++
+	}
+	return steps
+}
@@ -0,0 +1,142 @@
+package text2img
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFramesRevealBlanks(t *testing.T) {
+	snippets := []Snippet{
+		{Lines: []string{"I ...{went} to school."}},
+		{Lines: []string{"Fill in the ... yourself."}},
+	}
+
+	d := &drawer{}
+	if frames := d.frames(snippets); len(frames) != 2 {
+		t.Fatalf("blanks must not be revealed by default, got %d frames", len(frames))
+	}
+
+	d.SetRevealBlanks(true)
+	frames := d.frames(snippets)
+	if len(frames) != 3 {
+		t.Fatalf("an answered blank must be followed by a reveal frame, got %d frames", len(frames))
+	}
+	if frames[0].reveal || !frames[1].reveal || frames[1].step != 1 || frames[2].reveal {
+		t.Errorf("only the second frame must reveal the answer, got %+v", frames)
+	}
+	if blank := frames[1].lines()[0][1]; !blank.blank || !blank.reveal || blank.answer != "went" {
+		t.Errorf("the blank of the reveal frame must show its answer, got %+v", blank)
+	}
+}
+
+func TestBuildFrames(t *testing.T) {
+	snippets := []Snippet{
+		{Lines: []string{"First line.", "Second line.", "Third line."}},
+		{Lines: []string{"x := 1", "y := 2"}, Code: true},
+	}
+
+	d := &drawer{}
+	d.SetBuildFrames(true)
+	frames := d.frames(snippets)
+	if len(frames) != 4 {
+		t.Fatalf("a text snippet of 3 lines must be built in 3 frames, got %d frames", len(frames))
+	}
+	for i, visible := range []int{1, 2, 0, 0} {
+		if frames[i].visible != visible {
+			t.Errorf("frame %d must show %d lines, got %d", i, visible, frames[i].visible)
+		}
+	}
+	if frames[2].step != 2 || frames[3].step != 0 {
+		t.Errorf("frames must be numbered within their snippet, got steps %d and %d", frames[2].step, frames[3].step)
+	}
+}
+
+func TestFramesWithTransitions(t *testing.T) {
+	snippets := []Snippet{
+		{Lines: []string{"First slide."}},
+		{Lines: []string{"I ...{went} to school."}},
+		{},
+		{Lines: []string{"Last slide."}},
+	}
+
+	d := &drawer{}
+	d.SetRevealBlanks(true)
+	d.SetFrameRate(10)
+	d.SetTransition(TransitionFade, 0.3)
+	frames := d.frames(snippets)
+
+	transitions := make([]int, 0)
+	for _, f := range frames {
+		transitions = append(transitions, f.transition)
+	}
+	expected := []int{0, 1, 2, 3, 0, 0, 0, 1, 2, 3, 0}
+	if len(transitions) != len(expected) {
+		t.Fatalf("expected transitions %v, got %v", expected, transitions)
+	}
+	for i := range expected {
+		if transitions[i] != expected[i] {
+			t.Fatalf("expected transitions %v, got %v", expected, transitions)
+		}
+	}
+}
+
+func TestFrameSequenceFileNames(t *testing.T) {
+	frames := []frame{
+		{Snippet: Snippet{Lines: []string{"Two seconds."}, Options: SnippetOptions{Duration: 2}}},
+		{transition: 1},
+		{},
+		{Snippet: Snippet{Lines: []string{"Default duration."}}},
+		{Snippet: Snippet{Lines: []string{"Built", "in three steps."}, Options: SnippetOptions{Duration: 2.5}}},
+		{Snippet: Snippet{Lines: []string{"Built", "in three steps."}, Options: SnippetOptions{Duration: 2.5}}, step: 1},
+		{Snippet: Snippet{Lines: []string{"Built", "in three steps."}, Options: SnippetOptions{Duration: 2.5}}, step: 2},
+	}
+
+	d := &drawer{}
+	d.SetFrameRate(2)
+	d.SetFrameSequence(true, 1.5)
+	fileNames := d.fileNames(frames)
+
+	expected := [][]string{
+		{"000000.png", "000001.png", "000002.png", "000003.png"},
+		{"000004.png"},
+		nil,
+		{"000005.png", "000006.png", "000007.png"},
+		{"000008.png"},
+		{"000009.png", "000010.png"},
+		{"000011.png", "000012.png"},
+	}
+	if !reflect.DeepEqual(fileNames, expected) {
+		t.Errorf("expected %v, got %v", expected, fileNames)
+	}
+}
+
+func TestFrameSequenceWithMissingPlaceholder(t *testing.T) {
+	output, err := ioutil.TempDir("", "text2img")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(output)
+
+	d, err := NewDrawer(Params{Width: 40, Height: 20, FrameSequence: true, FrameRate: 1, Duration: 2, OutputFolder: output})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d.Draw("PLACEHOLDER_IMAGE missing.png")
+
+	files, err := ioutil.ReadDir(output)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		if filepath.Ext(file.Name()) == ".png" {
+			names = append(names, file.Name())
+		}
+	}
+	if expected := []string{"000000.png", "000001.png"}; !reflect.DeepEqual(names, expected) {
+		t.Errorf("missing placeholders must be drawn as the background, expected %v, got %v", expected, names)
+	}
+}
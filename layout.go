@@ -0,0 +1,178 @@
+package text2img
+
+import (
+	"image"
+	"math"
+	"strings"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// Align is the horizontal alignment of the lines of a snippet
+type Align string
+
+// Horizontal alignments
+const (
+	AlignCenter  Align = "center"
+	AlignLeft    Align = "left"
+	AlignRight   Align = "right"
+	AlignJustify Align = "justify"
+)
+
+// VerticalAlign is the vertical alignment of a snippet on the image
+type VerticalAlign string
+
+// Vertical alignments
+const (
+	VerticalAlignMiddle VerticalAlign = "middle"
+	VerticalAlignTop    VerticalAlign = "top"
+	VerticalAlignBottom VerticalAlign = "bottom"
+)
+
+// Margins are the spaces kept free on each side of the image, in pixels
+type Margins struct {
+	Top    int
+	Right  int
+	Bottom int
+	Left   int
+}
+
+// SafeArea is the part of each side of the image covered by the interface of a platform,
+// as a fraction of the width or height of the image
+type SafeArea struct {
+	Top    float64
+	Right  float64
+	Bottom float64
+	Left   float64
+}
+
+// SafeAreas are presets of the safe areas of vertical video platforms, selected by name
+var SafeAreas = map[string]SafeArea{
+	"youtube-shorts":  {Top: 0.12, Right: 0.14, Bottom: 0.22, Left: 0.05},
+	"instagram-story": {Top: 0.14, Right: 0.06, Bottom: 0.18, Left: 0.06},
+	"instagram-reels": {Top: 0.12, Right: 0.14, Bottom: 0.26, Left: 0.06},
+	"tiktok":          {Top: 0.1, Right: 0.16, Bottom: 0.22, Left: 0.06},
+}
+
+// textArea returns the part of the image text is laid out in: inside the margins and out of the safe area
+func (d *drawer) textArea() image.Rectangle {
+	inset := func(margin int, safeArea float64, size int) int {
+		return int(math.Max(float64(margin), math.Ceil(safeArea*float64(size))))
+	}
+	return image.Rect(
+		inset(d.Margins.Left, d.SafeArea.Left, d.Width),
+		inset(d.Margins.Top, d.SafeArea.Top, d.Height),
+		d.Width-inset(d.Margins.Right, d.SafeArea.Right, d.Width),
+		d.Height-inset(d.Margins.Bottom, d.SafeArea.Bottom, d.Height),
+	)
+}
+
+// alignments returns the alignments of a snippet, its own options overriding the drawer's
+func (d *drawer) alignments(options SnippetOptions) (Align, VerticalAlign) {
+	align, verticalAlign := d.Align, d.VerticalAlign
+	if options.Align != "" {
+		align = options.Align
+	}
+	if options.VerticalAlign != "" {
+		verticalAlign = options.VerticalAlign
+	}
+	return align, verticalAlign
+}
+
+// lineStart returns the x of the start of a line and the space added to each of its spaces.
+// Justified lines fill the width of the text area, except the last line of the snippet.
+func (d *drawer) lineStart(line []span, align Align, lastLine bool) (x, wordSpacing fixed.Int26_6) {
+	area := d.textArea()
+	width := d.measureSpans(line, d.FontSize)
+	free := fixed.I(area.Dx()) - width
+	switch align {
+	case AlignLeft:
+		x = fixed.I(area.Min.X)
+	case AlignRight:
+		x = fixed.I(area.Max.X) - width
+	case AlignJustify:
+		x = fixed.I(area.Min.X)
+		if spaces := countSpaces(line); !lastLine && spaces > 0 && free > 0 {
+			wordSpacing = free / fixed.Int26_6(spaces)
+		}
+	default:
+		x = fixed.I(area.Min.X) + free/2
+	}
+	return x + fixed.I(d.TextPosHorizontal), wordSpacing
+}
+
+// layoutLines returns the offset of the baseline of each line from the top of the block, and the height of the block.
+// Each line takes LineHeight times the font size, its glyphs centered in it from the ascent and descent of the face,
+// and lines ending a sentence are followed by ParagraphSpacing times the font size when paragraphs is set.
+func (d *drawer) layoutLines(lines [][]span, paragraphs bool, size float64) (baselines []fixed.Int26_6, height fixed.Int26_6) {
+	metrics := d.face(d.Font, size).Metrics()
+	lineHeight := fixed.Int26_6(d.LineHeight * size * 64)
+	halfLeading := (lineHeight - metrics.Ascent - metrics.Descent) / 2
+	paragraphSpacing := fixed.Int26_6(d.ParagraphSpacing * size * 64)
+
+	baselines = make([]fixed.Int26_6, len(lines))
+	for index := range lines {
+		if index > 0 && paragraphs && endsSentence(lines[index-1]) {
+			height += paragraphSpacing
+		}
+		baselines[index] = height + halfLeading + metrics.Ascent
+		height += lineHeight
+	}
+	return
+}
+
+// blockHeight returns the height of a block of lines at the given font size
+func (d *drawer) blockHeight(lines [][]span, paragraphs bool, fontSize float64) int {
+	_, height := d.layoutLines(lines, paragraphs, fontSize)
+	return height.Ceil()
+}
+
+// blockTop returns the y of the top of a block of lines laid out by layoutLines.
+// Blocks are placed by their glyphs rather than their line boxes: middle aligned blocks are centered
+// from the cap height of their first line to the descent of their last line, top and bottom aligned
+// blocks keep the ascent of their first line and the descent of their last line in the text area.
+func (d *drawer) blockTop(baselines []fixed.Int26_6, size float64, verticalAlign VerticalAlign) fixed.Int26_6 {
+	area := d.textArea()
+	if len(baselines) == 0 {
+		return fixed.I(area.Min.Y)
+	}
+	metrics := d.face(d.Font, size).Metrics()
+	first, last := baselines[0], baselines[len(baselines)-1]
+	switch verticalAlign {
+	case VerticalAlignTop:
+		return fixed.I(area.Min.Y) - (first - metrics.Ascent)
+	case VerticalAlignBottom:
+		return fixed.I(area.Max.Y) - (last + metrics.Descent)
+	}
+	inkTop := first - d.capHeight(size)
+	inkBottom := last + metrics.Descent
+	return fixed.I(area.Min.Y) + (fixed.I(area.Dy())-(inkBottom-inkTop))/2 - inkTop
+}
+
+// capHeight returns the height of capital letters above the baseline in the face of the drawer
+func (d *drawer) capHeight(size float64) fixed.Int26_6 {
+	face := d.face(d.Font, size)
+	if bounds, _, ok := face.GlyphBounds('H'); ok && bounds.Min.Y < 0 {
+		return -bounds.Min.Y
+	}
+	return face.Metrics().Ascent * 7 / 10
+}
+
+// endsSentence reports whether a line is the end of a sentence rather than a part of a split one
+func endsSentence(line []span) bool {
+	if len(line) == 0 {
+		return false
+	}
+	text := strings.TrimRight(line[len(line)-1].text, " \"'”’)")
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "!") || strings.HasSuffix(text, "?")
+}
+
+// countSpaces returns the number of spaces between the words of a line
+func countSpaces(line []span) (spaces int) {
+	for _, s := range line {
+		if !s.blank {
+			spaces += strings.Count(s.text, " ")
+		}
+	}
+	return
+}
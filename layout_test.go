@@ -0,0 +1,108 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"golang.org/x/image/math/fixed"
+)
+
+func TestLineStart(t *testing.T) {
+	d := newTestDrawer(t)
+	d.FontSize = 32
+	line := parseMarkup("Justified **lines** fill the width")
+	width := d.measureSpans(line, d.FontSize)
+
+	if x, _ := d.lineStart(line, AlignLeft, false); x != 0 {
+		t.Errorf("left aligned line must start at 0, got %v", x)
+	}
+	if x, _ := d.lineStart(line, AlignRight, false); x+width != fixed.I(d.Width) {
+		t.Errorf("right aligned line must end at %v, got %v", d.Width, x+width)
+	}
+	if x, _ := d.lineStart(line, AlignCenter, false); x != (fixed.I(d.Width)-width)/2 {
+		t.Errorf("centered line must start at %v, got %v", (fixed.I(d.Width)-width)/2, x)
+	}
+
+	x, wordSpacing := d.lineStart(line, AlignJustify, false)
+	if end := x + width + 4*wordSpacing; x != 0 || end > fixed.I(d.Width) || end < fixed.I(d.Width-1) {
+		t.Errorf("justified line must span the width, got %v to %v", x, end)
+	}
+	d.SetColors(color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255})
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, 100))
+	justified := parseMarkup("AVAT, To You. Wave **Ta** ye")
+	x, wordSpacing = d.lineStart(justified, AlignJustify, false)
+	expected := x + d.measureSpans(justified, d.FontSize) + fixed.Int26_6(countSpaces(justified))*wordSpacing
+	if dot := d.drawSpans(img, justified, d.FontSize, fixed.Point26_6{X: x, Y: fixed.I(50)}, wordSpacing); dot.X != expected {
+		t.Errorf("justified line must be drawn as it is measured, ending at %v, got %v", expected, dot.X)
+	}
+	if _, wordSpacing = d.lineStart(line, AlignJustify, true); wordSpacing != 0 {
+		t.Errorf("last line must not be justified, got a word spacing of %v", wordSpacing)
+	}
+}
+
+func TestTextArea(t *testing.T) {
+	d := &drawer{}
+	d.SetSize(1080, 1920)
+	d.SetMargins(Margins{Top: 40, Right: 40, Bottom: 40, Left: 100})
+	if err := d.SetSafeArea("youtube-shorts"); err != nil {
+		t.Fatal(err.Error())
+	}
+
+	expected := image.Rect(100, 231, 1080-152, 1920-423)
+	if area := d.textArea(); area != expected {
+		t.Errorf("text area must be %v, got %v", expected, area)
+	}
+
+	if err := d.SetSafeArea("unknown"); err == nil {
+		t.Errorf("unknown safe areas must be rejected")
+	}
+}
+
+func TestLayoutLines(t *testing.T) {
+	d := newTestDrawer(t)
+	d.SetLineSpacing(1.5, 0.5)
+	lines := [][]span{
+		parseMarkup("A first sentence."),
+		parseMarkup("A long sentence split after a comma,"),
+		parseMarkup("and ending here."),
+	}
+
+	baselines, height := d.layoutLines(lines, true, 40)
+	if baselines[1]-baselines[0] != fixed.I(80) || baselines[2]-baselines[1] != fixed.I(60) {
+		t.Errorf("sentences must be 60px apart plus 20px of paragraph spacing, got baselines %v", baselines)
+	}
+	if height != fixed.I(200) {
+		t.Errorf("block must be 200px high, got %v", height)
+	}
+
+	if _, height = d.layoutLines(lines, false, 40); height != fixed.I(180) {
+		t.Errorf("block without paragraphs must be 180px high, got %v", height)
+	}
+}
+
+func TestBlockTop(t *testing.T) {
+	d := newTestDrawer(t)
+	d.SetLineSpacing(1.5, 0)
+	lines := [][]span{parseMarkup("Optically centered"), parseMarkup("glyphs")}
+	baselines, _ := d.layoutLines(lines, false, 40)
+	metrics := d.face(d.Font, 40).Metrics()
+	capHeight := d.capHeight(40)
+	if capHeight <= 0 || capHeight >= metrics.Ascent {
+		t.Fatalf("cap height must be under the ascent, got %v", capHeight)
+	}
+
+	top := d.blockTop(baselines, 40, VerticalAlignMiddle)
+	above := top + baselines[0] - capHeight
+	below := fixed.I(d.Height) - (top + baselines[1] + metrics.Descent)
+	if diff := above - below; diff < -1 || diff > 1 {
+		t.Errorf("glyphs must be centered from the cap height to the descent, got %v above and %v below", above, below)
+	}
+
+	if top = d.blockTop(baselines, 40, VerticalAlignTop); top+baselines[0]-metrics.Ascent != 0 {
+		t.Errorf("top aligned block must start its ascent at 0, got %v", top+baselines[0]-metrics.Ascent)
+	}
+	if top = d.blockTop(baselines, 40, VerticalAlignBottom); top+baselines[1]+metrics.Descent != fixed.I(d.Height) {
+		t.Errorf("bottom aligned block must end its descent at %v, got %v", d.Height, top+baselines[1]+metrics.Descent)
+	}
+}
@@ -0,0 +1,196 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"strings"
+	"unicode"
+)
+
+// span is a run of text drawn in a single style
+type span struct {
+	text      string
+	bold      bool
+	italic    bool
+	underline bool
+	highlight bool
+	color     *image.Uniform
+
+	// code is drawn with the code font
+	code bool
+
+	// blank is a fill-in line drawn instead of text, with the answer shown when revealed
+	blank  bool
+	answer string
+	reveal bool
+}
+
+// markers toggling a style, longest first so that "**" wins over "*"
+var styleMarkers = []string{"**", "__", "==", "*"}
+
+// parseMarkup splits a line of notes into styled spans:
+//
+//	**bold**, *italic*, __underline__, ==highlighted== and {#d24136|colored text}
+//
+// and vocabulary blanks, optionally followed by their answer: "I ...{went} to school".
+//
+// A marker only opens when followed by a non-space and closed later in the line,
+// and only closes when preceded by a non-space, so "2 * 3 * 4" stays as it is.
+// A backslash makes the next character literal.
+func parseMarkup(line string) []span {
+	runes := []rune(line)
+	spans := make([]span, 0)
+	current := span{}
+	colors := make([]*image.Uniform, 0)
+	var text []rune
+
+	flush := func() {
+		if len(text) > 0 {
+			current.text = string(text)
+			spans = append(spans, current)
+			current.text = ""
+			text = nil
+		}
+	}
+	toggle := func(marker string) {
+		flush()
+		switch marker {
+		case "**":
+			current.bold = !current.bold
+		case "*":
+			current.italic = !current.italic
+		case "__":
+			current.underline = !current.underline
+		case "==":
+			current.highlight = !current.highlight
+		}
+	}
+	isOn := func(marker string) bool {
+		switch marker {
+		case "**":
+			return current.bold
+		case "*":
+			return current.italic
+		case "==":
+			return current.highlight
+		}
+		return current.underline
+	}
+
+	for i := 0; i < len(runes); i++ {
+		if runes[i] == '\\' && i+1 < len(runes) {
+			i++
+			text = append(text, runes[i])
+			continue
+		}
+
+		if marker := markerAt(runes, i); marker != "" {
+			if isOn(marker) && i > 0 && !unicode.IsSpace(runes[i-1]) {
+				toggle(marker)
+				i += len(marker) - 1
+				continue
+			}
+			if !isOn(marker) && closes(runes, i, marker) {
+				toggle(marker)
+				i += len(marker) - 1
+				continue
+			}
+		}
+
+		if answer, end, ok := blankAt(runes, i); ok {
+			flush()
+			blank := current
+			blank.blank, blank.answer = true, answer
+			spans = append(spans, blank)
+			i = end
+			continue
+		}
+
+		if c, end, ok := colorAt(runes, i); ok {
+			flush()
+			colors = append(colors, current.color)
+			current.color = image.NewUniform(c)
+			i = end
+			continue
+		}
+
+		if runes[i] == '}' && len(colors) > 0 {
+			flush()
+			current.color = colors[len(colors)-1]
+			colors = colors[:len(colors)-1]
+			continue
+		}
+
+		text = append(text, runes[i])
+	}
+	flush()
+	return spans
+}
+
+// markerAt returns the style marker starting at runes[i], if any
+func markerAt(runes []rune, i int) string {
+	for _, marker := range styleMarkers {
+		if strings.HasPrefix(string(runes[i:]), marker) {
+			return marker
+		}
+	}
+	return ""
+}
+
+// blankAt parses a vocabulary blank "..." at runes[i], with its optional "{answer}", and returns the index of its last rune.
+// Dots right after a word are an ellipsis, not a blank: "Well... maybe".
+func blankAt(runes []rune, i int) (answer string, end int, ok bool) {
+	if !strings.HasPrefix(string(runes[i:]), "...") {
+		return
+	}
+	if i > 0 && (unicode.IsLetter(runes[i-1]) || unicode.IsDigit(runes[i-1])) {
+		return
+	}
+	end = i + 2
+	if end+1 < len(runes) && runes[end+1] == '{' {
+		for j := end + 2; j < len(runes); j++ {
+			if runes[j] == '}' {
+				return string(runes[end+2 : j]), j, true
+			}
+		}
+	}
+	return "", end, true
+}
+
+// colorAt parses the opening "{#rrggbb|" of a color span at runes[i] and returns the index of its "|".
+// The span must be closed by a "}" later in the line.
+func colorAt(runes []rune, i int) (c color.RGBA, end int, ok bool) {
+	if runes[i] != '{' || i+1 >= len(runes) || runes[i+1] != '#' {
+		return
+	}
+	for end = i + 2; end < len(runes) && runes[end] != '|'; end++ {
+	}
+	if end == len(runes) || !strings.ContainsRune(string(runes[end:]), '}') {
+		return
+	}
+	c, err := Hex(string(runes[i+1 : end]))
+	return c, end, err == nil
+}
+
+// closes reports whether the marker at runes[i] opens a span closed later in the line
+func closes(runes []rune, i int, marker string) bool {
+	start := i + len(marker)
+	if start >= len(runes) || unicode.IsSpace(runes[start]) {
+		return false
+	}
+	for j := start + 1; j < len(runes); j++ {
+		if runes[j] == '\\' {
+			j++
+			continue
+		}
+		if markerAt(runes, j) == marker && !unicode.IsSpace(runes[j-1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// plainSpans returns the line as a single unstyled span, used for code
+func plainSpans(line string) []span {
+	return []span{{text: line, code: true}}
+}
@@ -0,0 +1,43 @@
+package text2img
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkup(t *testing.T) {
+	red := must(Hex("#d24136"))
+
+	tests := []struct {
+		line  string
+		spans []span
+	}{
+		{"plain text", []span{{text: "plain text"}}},
+		{"a **bold** word", []span{{text: "a "}, {text: "bold", bold: true}, {text: " word"}}},
+		{"*italic* and __underlined__", []span{{text: "italic", italic: true}, {text: " and "}, {text: "underlined", underline: true}}},
+		{"***both***", []span{{text: "both", bold: true, italic: true}}},
+		{"learn ==vocabulary== daily", []span{{text: "learn "}, {text: "vocabulary", highlight: true}, {text: " daily"}}},
+		{"I ...{went} to school", []span{{text: "I "}, {blank: true, answer: "went"}, {text: " to school"}}},
+		{"... is a blank", []span{{blank: true}, {text: " is a blank"}}},
+		{"Well... maybe", []span{{text: "Well... maybe"}}},
+		{"2 * 3 * 4", []span{{text: "2 * 3 * 4"}}},
+		{"**unclosed", []span{{text: "**unclosed"}}},
+		{`\*literal\*`, []span{{text: "*literal*"}}},
+		{"a {#d24136|red} word", []span{{text: "a "}, {text: "red"}, {text: " word"}}},
+		{"{not a color}", []span{{text: "{not a color}"}}},
+	}
+	for _, test := range tests {
+		spans := parseMarkup(test.line)
+		for i := range spans {
+			if spans[i].color != nil {
+				if spans[i].color.C != red {
+					t.Errorf("%q: span %q must be red, got %v", test.line, spans[i].text, spans[i].color.C)
+				}
+				spans[i].color = nil
+			}
+		}
+		if !reflect.DeepEqual(spans, test.spans) {
+			t.Errorf("%q must be parsed as %+v, got %+v", test.line, test.spans, spans)
+		}
+	}
+}
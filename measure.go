@@ -0,0 +1,232 @@
+package text2img
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/math/fixed"
+)
+
+// fontFor returns the font used to draw r: f when it has a glyph for r,
+// otherwise the first fallback font that does, f being nil or not. f is kept when no font has the glyph.
+func (d *drawer) fontFor(f *truetype.Font, r rune) *truetype.Font {
+	if f != nil && f.Index(r) != 0 {
+		return f
+	}
+	for _, fallback := range d.FallbackFonts {
+		if fallback.Index(r) != 0 {
+			return fallback
+		}
+	}
+	return f
+}
+
+// glyph is either a rune drawn with a font or an emoji image spanning one or more runes
+type glyph struct {
+	r     rune
+	font  *truetype.Font
+	emoji string
+}
+
+// glyphs splits text into the glyphs drawn for it, resolving fallback fonts and emoji sequences
+func (d *drawer) glyphs(f *truetype.Font, text string) []glyph {
+	runes := []rune(text)
+	glyphs := make([]glyph, 0, len(runes))
+	for i := 0; i < len(runes); i++ {
+		if name, n := d.emoji.match(runes, i); n > 0 {
+			glyphs = append(glyphs, glyph{emoji: name})
+			i += n - 1
+			continue
+		}
+		if isInvisible(runes[i]) {
+			continue
+		}
+		glyphs = append(glyphs, glyph{r: runes[i], font: d.fontFor(f, runes[i])})
+	}
+	return glyphs
+}
+
+// advance returns the advance of g, kerned against the previous glyph
+func (d *drawer) advance(prev, g glyph, size float64) (a fixed.Int26_6) {
+	if g.emoji != "" {
+		return fixed.Int26_6(size * 64)
+	}
+	if prev.emoji == "" && prev.font == g.font && prev.r != 0 {
+		a += d.face(g.font, size).Kern(prev.r, g.r)
+	}
+	width, _ := d.glyphAdvance(g.font, size, g.r)
+	return a + width
+}
+
+// measureString returns the advance of text in the face of f at the given size.
+// Advances are accumulated in fixed point and kerned pair by pair, exactly as drawString lays them out.
+// Runes missing from f are measured with the fallback fonts, and emoji take a square of the font size.
+func (d *drawer) measureString(f *truetype.Font, size float64, text string) (width fixed.Int26_6) {
+	var prev glyph
+	for _, g := range d.glyphs(f, text) {
+		width += d.advance(prev, g, size)
+		prev = g
+	}
+	return
+}
+
+// drawString draws text onto dst with its baseline starting at dot and returns the dot after the last glyph
+func (d *drawer) drawString(dst draw.Image, src image.Image, f *truetype.Font, size float64, dot fixed.Point26_6, text string) fixed.Point26_6 {
+	return d.drawSpacedString(dst, src, f, size, dot, text, 0)
+}
+
+// drawSpacedString draws text like drawString, adding wordSpacing after each space.
+// Glyphs are kerned across spaces as they are when measured.
+func (d *drawer) drawSpacedString(dst draw.Image, src image.Image, f *truetype.Font, size float64, dot fixed.Point26_6, text string, wordSpacing fixed.Int26_6) fixed.Point26_6 {
+	var prev glyph
+	for _, g := range d.glyphs(f, text) {
+		a := d.advance(prev, g, size)
+		if g.emoji != "" {
+			d.drawEmoji(dst, f, size, dot, g.emoji)
+			dot.X += a
+			prev = g
+			continue
+		}
+		width, _ := d.glyphAdvance(g.font, size, g.r)
+		dot.X += a - width
+		dr, mask, maskp, _, ok := d.face(g.font, size).Glyph(dot, g.r)
+		if ok {
+			draw.DrawMask(dst, dr, src, image.ZP, mask, maskp, draw.Over)
+		}
+		dot.X += width
+		if g.r == ' ' {
+			dot.X += wordSpacing
+		}
+		prev = g
+	}
+	return dot
+}
+
+// drawEmoji draws an emoji at dot, vertically centered on the line of the face of f
+func (d *drawer) drawEmoji(dst draw.Image, f *truetype.Font, size float64, dot fixed.Point26_6, name string) {
+	px := int(size)
+	img, err := d.emoji.image(name, px)
+	if err != nil {
+		return
+	}
+	metrics := d.face(f, size).Metrics()
+	top := dot.Y - metrics.Ascent + (metrics.Ascent+metrics.Descent-fixed.I(px))/2
+	pt := image.Pt(dot.X.Round(), top.Round())
+	draw.Draw(dst, img.Bounds().Add(pt), img, image.ZP, draw.Over)
+}
+
+// styleFont returns the font of a span's style, falling back to the closest style loaded
+func (d *drawer) styleFont(s span) *truetype.Font {
+	candidates := []*truetype.Font{d.Font}
+	switch {
+	case s.code:
+		candidates = []*truetype.Font{d.CodeFont, d.Font}
+	case s.bold && s.italic:
+		candidates = []*truetype.Font{d.BoldItalicFont, d.BoldFont, d.ItalicFont, d.Font}
+	case s.bold:
+		candidates = []*truetype.Font{d.BoldFont, d.Font}
+	case s.italic:
+		candidates = []*truetype.Font{d.ItalicFont, d.Font}
+	}
+	for _, f := range candidates {
+		if f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// measureSpans returns the advance of a line made of styled spans
+func (d *drawer) measureSpans(spans []span, size float64) (width fixed.Int26_6) {
+	for _, s := range spans {
+		width += d.spanWidth(s, size)
+	}
+	return
+}
+
+// spanWidth returns the advance of a span. Blanks are wide enough for their answer,
+// so that the layout doesn't move when the answer is revealed.
+func (d *drawer) spanWidth(s span, size float64) fixed.Int26_6 {
+	if !s.blank {
+		return d.measureString(d.styleFont(s), size, s.text)
+	}
+	width := fixed.Int26_6(d.BlankWidth * size * 64)
+	if s.answer != "" {
+		answerWidth := d.measureString(d.styleFont(s), size, s.answer) + fixed.Int26_6(size*64/2)
+		if answerWidth > width {
+			width = answerWidth
+		}
+	}
+	return width
+}
+
+// spanAdvance returns the advance of a span drawn with wordSpacing added to each of its spaces
+func (d *drawer) spanAdvance(s span, size float64, wordSpacing fixed.Int26_6) fixed.Int26_6 {
+	width := d.spanWidth(s, size)
+	if !s.blank {
+		width += wordSpacing * fixed.Int26_6(strings.Count(s.text, " "))
+	}
+	return width
+}
+
+// drawSpans draws a line made of styled spans with its baseline starting at dot, adding wordSpacing to each space.
+// Highlights are painted first so that they never cover the glyphs of neighbouring spans.
+func (d *drawer) drawSpans(dst draw.Image, spans []span, size float64, dot fixed.Point26_6, wordSpacing fixed.Int26_6) fixed.Point26_6 {
+	d.drawHighlights(dst, spans, size, dot, wordSpacing)
+	return d.drawGlyphs(dst, dst, spans, size, dot, wordSpacing)
+}
+
+// drawHighlights paints the highlights of a line of spans with its baseline starting at dot
+func (d *drawer) drawHighlights(dst draw.Image, spans []span, size float64, dot fixed.Point26_6, wordSpacing fixed.Int26_6) {
+	for _, s := range spans {
+		width := d.spanAdvance(s, size, wordSpacing)
+		if s.highlight {
+			d.drawHighlight(dst, d.styleFont(s), size, dot, dot.X+width)
+		}
+		dot.X += width
+	}
+}
+
+// drawGlyphs draws the glyphs of a line of spans onto dst and their underlines and blank lines onto rules,
+// so that the outline and the shadow of text can be built from its glyphs only
+func (d *drawer) drawGlyphs(dst, rules draw.Image, spans []span, size float64, dot fixed.Point26_6, wordSpacing fixed.Int26_6) fixed.Point26_6 {
+	thickness := int(math.Max(1, math.Round(size/16)))
+	for _, s := range spans {
+		var src image.Image = d.TextColor
+		if s.color != nil {
+			src = s.color
+		}
+		start := dot
+		if s.blank {
+			dot.X += d.spanWidth(s, size)
+			if s.reveal && s.answer != "" {
+				answerWidth := d.measureString(d.styleFont(s), size, s.answer)
+				answerDot := fixed.Point26_6{X: start.X + (dot.X-start.X-answerWidth)/2, Y: dot.Y}
+				d.drawString(dst, d.AccentColor, d.styleFont(s), size, answerDot, s.answer)
+			}
+		} else {
+			dot = d.drawSpacedString(dst, src, d.styleFont(s), size, dot, s.text, wordSpacing)
+		}
+		if s.underline || s.blank {
+			top := dot.Y.Round() + thickness
+			draw.Draw(rules, image.Rect(start.X.Round(), top, dot.X.Round(), top+thickness), src, image.ZP, draw.Over)
+		}
+	}
+	return dot
+}
+
+// drawHighlight paints a marker-pen box in the accent color behind the text between dot and endX
+func (d *drawer) drawHighlight(dst draw.Image, f *truetype.Font, size float64, dot fixed.Point26_6, endX fixed.Int26_6) {
+	metrics := d.face(f, size).Metrics()
+	padding := fixed.Int26_6(size * 64 * 0.15)
+	rect := image.Rect(
+		(dot.X - padding).Floor(),
+		(dot.Y - metrics.Ascent).Floor(),
+		(endX + padding).Ceil(),
+		(dot.Y + metrics.Descent).Ceil(),
+	)
+	drawRoundedRect(dst, rect, size*0.2, d.AccentColor)
+}
@@ -0,0 +1,129 @@
+package text2img
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"testing"
+
+	"github.com/golang/freetype"
+	"github.com/golang/freetype/truetype"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func newTestDrawer(t *testing.T) *drawer {
+	f, err := freetype.ParseFont(goregular.TTF)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	d := &drawer{Font: f}
+	d.SetSize(0, 0)
+	return d
+}
+
+func TestMeasureStringMatchesDrawString(t *testing.T) {
+	d := newTestDrawer(t)
+	text := "AVATAR Wave, To you. Tallying"
+	width := d.measureString(d.Font, 48, text)
+
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	dot := d.drawString(img, image.Black, d.Font, 48, fixed.P(0, 100), text)
+	if dot.X != width {
+		t.Errorf("drawn advance must equal the measured width %v, got %v", width, dot.X)
+	}
+}
+
+func TestMeasureStringKeepsCountingAfterMissingGlyph(t *testing.T) {
+	d := newTestDrawer(t)
+	text := "ab世cd"
+	face := truetype.NewFace(d.Font, &truetype.Options{Size: 32})
+	notdef, ok := face.GlyphAdvance('世')
+	if d.Font.Index('世') != 0 || !ok || notdef == 0 {
+		t.Fatalf("the test font must miss 世 and draw it with a notdef glyph, got advance %v", notdef)
+	}
+
+	// Every advance counts, the notdef one included, kerned pair by pair
+	var expected fixed.Int26_6
+	var prev rune
+	for _, r := range text {
+		if prev != 0 {
+			expected += face.Kern(prev, r)
+		}
+		a, _ := face.GlyphAdvance(r)
+		expected += a
+		prev = r
+	}
+	if width := d.measureString(d.Font, 32, text); width != expected {
+		t.Errorf("width with a missing glyph must be the sum of every advance %v, got %v", expected, width)
+	}
+}
+
+// fontWithout returns Go Regular without a glyph for r, which must be the only rune of its cmap segment
+func fontWithout(t *testing.T, r rune) *truetype.Font {
+	ttf := append([]byte(nil), goregular.TTF...)
+	u16 := func(i int) int { return int(binary.BigEndian.Uint16(ttf[i:])) }
+	for i := 0; i < u16(4); i++ {
+		record := 12 + 16*i
+		if string(ttf[record:record+4]) != "cmap" {
+			continue
+		}
+		cmap := int(binary.BigEndian.Uint32(ttf[record+8:]))
+		// Format 4 subtable: end codes, a reserved pad, then start codes
+		subtable := cmap + int(binary.BigEndian.Uint32(ttf[cmap+8:]))
+		segments := u16(subtable+6) / 2
+		for s := 0; s < segments; s++ {
+			end, start := subtable+14+2*s, subtable+16+2*segments+2*s
+			if u16(end) == int(r) && u16(start) == int(r) {
+				// A segment starting after its end maps no rune
+				binary.BigEndian.PutUint16(ttf[start:], uint16(r+1))
+			}
+		}
+	}
+	f, err := freetype.ParseFont(ttf)
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if f.Index(r) != 0 {
+		t.Fatalf("%q must be missing from the font", r)
+	}
+	return f
+}
+
+func TestFallbackFonts(t *testing.T) {
+	d := newTestDrawer(t)
+	primary := fontWithout(t, '€')
+	d.Font, d.FallbackFonts = primary, []*truetype.Font{d.Font}
+	fallback := d.FallbackFonts[0]
+
+	if f := d.fontFor(primary, '€'); f != fallback {
+		t.Errorf("runes missing from the font must use the fallback font")
+	}
+	if f := d.fontFor(primary, 'A'); f != primary {
+		t.Errorf("runes of the font must use the font")
+	}
+
+	// Kerning only applies within a font, so the width is the sum of the advances
+	a, _ := d.glyphAdvance(primary, 32, 'A')
+	euro, _ := d.glyphAdvance(fallback, 32, '€')
+	if width := d.measureString(primary, 32, "A€"); width != a+euro {
+		t.Errorf("width must count the fallback advance %v, got %v", a+euro, width-a)
+	}
+
+	// The euro is drawn from the fallback font rather than as the notdef box of the font
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	d.drawString(img, image.Black, primary, 32, fixed.P(0, 40), "€")
+	expected := image.NewRGBA(img.Bounds())
+	d.drawString(expected, image.Black, fallback, 32, fixed.P(0, 40), "€")
+	if !bytes.Equal(img.Pix, expected.Pix) {
+		t.Error("runes missing from the font must be drawn with the fallback font")
+	}
+
+	d = &drawer{FallbackFonts: []*truetype.Font{fallback}}
+	if f := d.fontFor(nil, '€'); f != fallback {
+		t.Errorf("runes must use the fallback fonts when the drawer has no font")
+	}
+	if err := d.SetFallbackFontPaths("go-regular"); err != nil || d.Font != d.FallbackFonts[0] {
+		t.Errorf("the first fallback font must be the font of drawers without one, got %v", err)
+	}
+}
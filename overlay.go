@@ -0,0 +1,150 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"golang.org/x/image/math/fixed"
+)
+
+// OverlayKind is the way an overlay keeps text readable over a background image
+type OverlayKind string
+
+// Overlay kinds
+const (
+	// OverlayNone draws the background image as is
+	OverlayNone OverlayKind = ""
+	// OverlayDim covers the whole image with the overlay color
+	OverlayDim OverlayKind = "dim"
+	// OverlayScrim covers the text with the overlay color, fading out away from it
+	OverlayScrim OverlayKind = "scrim"
+	// OverlayBlur blurs the image so that its details don't compete with the text
+	OverlayBlur OverlayKind = "blur"
+	// OverlayBox draws a box with rounded corners in the overlay color behind the text
+	OverlayBox OverlayKind = "box"
+)
+
+// Overlay is drawn over background images to keep text readable
+type Overlay struct {
+	Kind OverlayKind
+	// Color is the color dims, scrims and boxes are drawn with, black when transparent
+	Color color.RGBA
+	// Opacity is the opacity of the overlay color, between 0 and 1
+	Opacity float64
+	// Radius is the radius of the blur in pixels
+	Radius float64
+}
+
+// textBounds returns the rectangle covered by the lines of a snippet laid out by layoutLines from top
+func (d *drawer) textBounds(lines [][]span, baselines []fixed.Int26_6, top fixed.Int26_6, align Align) image.Rectangle {
+	if len(lines) == 0 || d.Font == nil {
+		return d.textArea()
+	}
+	metrics := d.face(d.Font, d.FontSize).Metrics()
+	bounds := image.Rectangle{}
+	for index, line := range lines {
+		x, _ := d.lineStart(line, align, true)
+		width := d.measureSpans(line, d.FontSize)
+		lineBounds := image.Rect(
+			x.Floor(),
+			(top + baselines[index] - metrics.Ascent).Floor(),
+			(x + width).Ceil(),
+			(top + baselines[index] + metrics.Descent).Ceil(),
+		)
+		bounds = bounds.Union(lineBounds)
+	}
+	return bounds
+}
+
+// drawOverlay draws the overlay of the drawer on img, around text covering bounds
+func (d *drawer) drawOverlay(img *image.RGBA, bounds image.Rectangle) {
+	overlay := image.NewUniform(color.RGBA{
+		uint8(float64(d.Overlay.Color.R) * d.Overlay.Opacity),
+		uint8(float64(d.Overlay.Color.G) * d.Overlay.Opacity),
+		uint8(float64(d.Overlay.Color.B) * d.Overlay.Opacity),
+		uint8(255 * d.Overlay.Opacity),
+	})
+
+	switch d.Overlay.Kind {
+	case OverlayDim:
+		draw.Draw(img, img.Bounds(), overlay, image.ZP, draw.Over)
+	case OverlayScrim:
+		draw.DrawMask(img, img.Bounds(), overlay, image.ZP, scrimMask(img.Bounds(), bounds), image.ZP, draw.Over)
+	case OverlayBlur:
+		blur(img, d.Overlay.Radius)
+	case OverlayBox:
+		padding := int(math.Ceil(d.FontSize * 0.5))
+		drawRoundedRect(img, bounds.Inset(-padding), d.FontSize*0.3, overlay)
+	}
+}
+
+// scrimMask returns a mask fully opaque on the rows of bounds, fading out over a third of the image above and below
+func scrimMask(rect, bounds image.Rectangle) *image.Alpha {
+	mask := image.NewAlpha(rect)
+	fade := float64(rect.Dy()) / 3
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		distance := math.Max(float64(bounds.Min.Y-y), float64(y-bounds.Max.Y))
+		alpha := 1 - math.Max(0, distance)/fade
+		if alpha <= 0 {
+			continue
+		}
+		// Ease the fade out so that the edge of the scrim is not visible
+		alpha = alpha * alpha * (3 - 2*alpha)
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			mask.SetAlpha(x, y, color.Alpha{uint8(alpha * 255)})
+		}
+	}
+	return mask
+}
+
+// blur applies an approximate gaussian blur of the given radius to img, as three successive box blurs
+func blur(img *image.RGBA, radius float64) {
+	if radius < 1 {
+		return
+	}
+	// Box radius of three passes approximating a gaussian of standard deviation radius/2
+	sigma := radius / 2
+	box := int(math.Round((math.Sqrt(4*sigma*sigma+1) - 1) / 2))
+	if box < 1 {
+		box = 1
+	}
+	tmp := image.NewRGBA(img.Bounds())
+	for pass := 0; pass < 3; pass++ {
+		boxBlur(tmp, img, box, 4, img.Stride)
+		boxBlur(img, tmp, box, img.Stride, 4)
+	}
+}
+
+// boxBlur averages each pixel of src with its neighbours within radius along one direction into dst.
+// step is the offset of the next pixel in the direction and lineStep the offset of the next line.
+func boxBlur(dst, src *image.RGBA, radius, step, lineStep int) {
+	size := src.Bounds().Size()
+	length, lines := size.X, size.Y
+	if step != 4 {
+		length, lines = size.Y, size.X
+	}
+	window := 2*radius + 1
+	for line := 0; line < lines; line++ {
+		start := line * lineStep
+		for c := 0; c < 4; c++ {
+			at := func(i int) int {
+				if i < 0 {
+					i = 0
+				} else if i >= length {
+					i = length - 1
+				}
+				return int(src.Pix[start+i*step+c])
+			}
+			sum := 0
+			for i := -radius; i <= radius; i++ {
+				sum += at(i)
+			}
+			for i := 0; i < length; i++ {
+				dst.Pix[start+i*step+c] = uint8(sum / window)
+				sum += at(i+radius+1) - at(i-radius)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestBlur(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	for y := 0; y < 32; y++ {
+		for x := 32; x < 64; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+	blur(img, 8)
+
+	if c := img.RGBAAt(0, 16); c.R != 0 || c.A != 0 {
+		t.Errorf("pixels far from the edge must keep their color, got %v", c)
+	}
+	if c := img.RGBAAt(63, 0); c.R != 255 {
+		t.Errorf("pixels far from the edge must keep their color, got %v", c)
+	}
+	left, right := img.RGBAAt(30, 16).R, img.RGBAAt(33, 16).R
+	if left == 0 || right == 255 || left >= right {
+		t.Errorf("the edge must be smoothed, got %v and %v", left, right)
+	}
+}
+
+func TestScrimMask(t *testing.T) {
+	rect := image.Rect(0, 0, 10, 300)
+	mask := scrimMask(rect, image.Rect(0, 140, 10, 160))
+	if a := mask.AlphaAt(5, 150).A; a != 255 {
+		t.Errorf("scrim must be opaque behind the text, got %v", a)
+	}
+	if a := mask.AlphaAt(5, 0).A; a != 0 {
+		t.Errorf("scrim must fade out away from the text, got %v", a)
+	}
+	if above, below := mask.AlphaAt(5, 110).A, mask.AlphaAt(5, 120).A; above == 0 || above >= below {
+		t.Errorf("scrim must fade out progressively, got %v and %v", above, below)
+	}
+}
+
+func TestTextBounds(t *testing.T) {
+	d := newTestDrawer(t)
+	d.FontSize = 32
+	d.SetAlign(AlignCenter, VerticalAlignMiddle)
+	d.SetLineSpacing(0, 0)
+	lines := [][]span{parseMarkup("A short line"), parseMarkup("and a much longer line under it")}
+	baselines, _ := d.layoutLines(lines, true, d.FontSize)
+	top := d.blockTop(baselines, d.FontSize, VerticalAlignMiddle)
+
+	bounds := d.textBounds(lines, baselines, top, AlignCenter)
+	width := d.measureSpans(lines[1], d.FontSize).Ceil()
+	if bounds.Dx() < width || bounds.Dx() > width+2 {
+		t.Errorf("bounds must be as wide as the longest line, got %v", bounds)
+	}
+	if bounds.Min.Y >= (top+baselines[0]).Floor() || bounds.Max.Y <= (top+baselines[1]).Ceil() {
+		t.Errorf("bounds must cover every line, got %v", bounds)
+	}
+
+	d.SetOverlay(Overlay{Kind: OverlayDim})
+	if d.Overlay != (Overlay{Kind: OverlayDim, Color: color.RGBA{0, 0, 0, 255}, Opacity: 0.5, Radius: 8}) {
+		t.Errorf("unexpected overlay defaults %+v", d.Overlay)
+	}
+	if err := d.SetOverlay(Overlay{Kind: "fog"}); err == nil || d.Overlay.Kind != OverlayDim {
+		t.Errorf("unknown overlays must be rejected, got %v", err)
+	}
+	if _, err := NewDrawer(Params{Overlay: Overlay{Kind: "fog"}}); err == nil {
+		t.Error("drawers with an unknown overlay must not be created")
+	}
+}
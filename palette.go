@@ -0,0 +1,211 @@
+package text2img
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/freetype"
+	"golang.org/x/image/math/fixed"
+)
+
+// Palettes are the named palettes selectable by Params.PaletteName. The built-in colors are named "default".
+var Palettes = map[string][]Color{}
+
+// FindPalette returns the palette of that name, or loads the palette file at that path
+func FindPalette(nameOrPath string) ([]Color, error) {
+	palette, _, err := findPalette(nameOrPath)
+	return palette, err
+}
+
+// findPalette is FindPalette also returning the gradients and patterns of the palette, indexed like its colors
+func findPalette(nameOrPath string) ([]Color, []*Background, error) {
+	if palette, ok := Palettes[nameOrPath]; ok {
+		return palette, nil, nil
+	}
+	return loadPalette(nameOrPath)
+}
+
+// LoadPalette loads a palette from a JSON, CSV or GIMP .gpl file.
+// JSON palettes are lists of {"background": "#1e1f26", "text": "#fff"} objects and CSV palettes have a background
+// and an optional text column. GIMP palettes only have backgrounds. Colors without a text color get white or dark
+// text, whichever is more readable on their background.
+func LoadPalette(path string) ([]Color, error) {
+	palette, _, err := loadPalette(path)
+	return palette, err
+}
+
+// loadPalette is LoadPalette also returning the gradients and patterns of JSON palette entries
+func loadPalette(path string) ([]Color, []*Background, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var palette []Color
+	var backgrounds []*Background
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		palette, backgrounds, err = parseJSONPalette(src)
+	case ".csv":
+		palette, err = parseCSVPalette(bytes.NewReader(src))
+	case ".gpl":
+		palette, err = parseGIMPPalette(bytes.NewReader(src))
+	default:
+		err = fmt.Errorf("palette %s must be a .json, .csv or .gpl file", path)
+	}
+	if err == nil && len(palette) == 0 {
+		err = fmt.Errorf("palette %s has no color", path)
+	}
+	return palette, backgrounds, err
+}
+
+// paletteColor returns the pair of a background and a text color given as hex colors, the text color being optional
+func paletteColor(background, text string) (Color, error) {
+	c := Color{}
+	var err error
+	if c.BackgroundColor, err = Hex(strings.TrimSpace(background)); err != nil {
+		return c, fmt.Errorf("background %q: %s", background, err.Error())
+	}
+	if strings.TrimSpace(text) == "" {
+		c.TextColor = readableTextColor(c.BackgroundColor)
+		return c, nil
+	}
+	if c.TextColor, err = Hex(strings.TrimSpace(text)); err != nil {
+		return c, fmt.Errorf("text color %q: %s", text, err.Error())
+	}
+	return c, nil
+}
+
+// color returns the pair of colors of a palette entry, and its gradient or pattern if it has one
+func (entry ThemeColor) color() (Color, *Background, error) {
+	if entry.Kind == BackgroundSolid {
+		c, err := paletteColor(entry.Background, entry.Text)
+		return c, nil, err
+	}
+	background := &Background{Kind: entry.Kind, Angle: entry.Angle, Scale: entry.Scale}
+	for _, value := range entry.Colors {
+		c, err := Hex(strings.TrimSpace(value))
+		if err != nil {
+			return Color{}, nil, fmt.Errorf("%s color %q: %s", entry.Kind, value, err.Error())
+		}
+		background.Colors = append(background.Colors, c)
+	}
+	base := entry.Background
+	if base == "" && len(background.Colors) > 0 {
+		base = HexString(background.average(background.Colors[0]))
+	}
+	c, err := paletteColor(base, entry.Text)
+	return c, background, err
+}
+
+func parseJSONPalette(src []byte) ([]Color, []*Background, error) {
+	var entries []ThemeColor
+	if err := json.Unmarshal(src, &entries); err != nil {
+		return nil, nil, err
+	}
+	palette := make([]Color, 0, len(entries))
+	backgrounds := make([]*Background, 0, len(entries))
+	for _, entry := range entries {
+		c, background, err := entry.color()
+		if err != nil {
+			return nil, nil, err
+		}
+		palette = append(palette, c)
+		backgrounds = append(backgrounds, background)
+	}
+	return palette, backgrounds, nil
+}
+
+func parseCSVPalette(r io.Reader) ([]Color, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	palette := make([]Color, 0, len(records))
+	for index, record := range records {
+		// The first record may be a header like "background,text"
+		if index == 0 && !strings.HasPrefix(record[0], "#") {
+			continue
+		}
+		text := ""
+		if len(record) > 1 {
+			text = record[1]
+		}
+		c, err := paletteColor(record[0], text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", index+1, err.Error())
+		}
+		palette = append(palette, c)
+	}
+	return palette, nil
+}
+
+func parseGIMPPalette(r io.Reader) ([]Color, error) {
+	scanner := bufio.NewScanner(r)
+	palette := make([]Color, 0)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			if text != "GIMP Palette" {
+				return nil, fmt.Errorf("GIMP palettes must start with \"GIMP Palette\", got %q", text)
+			}
+			continue
+		}
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, "Name:") || strings.HasPrefix(text, "Columns:") {
+			continue
+		}
+		var r, g, b uint8
+		if _, err := fmt.Sscanf(text, "%d %d %d", &r, &g, &b); err != nil {
+			return nil, fmt.Errorf("line %d: %q is not a color: %s", line, text, err.Error())
+		}
+		background := color.RGBA{r, g, b, 255}
+		palette = append(palette, Color{background, readableTextColor(background)})
+	}
+	return palette, scanner.Err()
+}
+
+// readableTextColor returns white or dark gray text, whichever contrasts most with the background
+func readableTextColor(background color.RGBA) color.RGBA {
+	light, dark := must(Hex("#fff")), must(Hex("#333"))
+	if ContrastRatio(dark, background) > ContrastRatio(light, background) {
+		return dark
+	}
+	return light
+}
+
+// DrawPalette draws a swatch sheet of a palette: each pair of colors as a tile showing its hex codes
+func DrawPalette(palette []Color) (image.Image, error) {
+	const columns, tileWidth, tileHeight, fontSize = 4, 300, 160, 22
+	f, err := freetype.ParseFont(BuiltinFonts["go-mono"])
+	if err != nil {
+		return nil, err
+	}
+	d := &drawer{Font: f}
+
+	rows := (len(palette) + columns - 1) / columns
+	img := image.NewRGBA(image.Rect(0, 0, columns*tileWidth, rows*tileHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.ZP, draw.Src)
+	for index, c := range palette {
+		tile := image.Rect(0, 0, tileWidth, tileHeight).Add(image.Pt(index%columns*tileWidth, index/columns*tileHeight))
+		draw.Draw(img, tile, image.NewUniform(c.BackgroundColor), image.ZP, draw.Src)
+
+		text := image.NewUniform(c.TextColor)
+		for i, line := range []string{"Aa " + HexString(c.BackgroundColor), "text " + HexString(c.TextColor)} {
+			dot := fixed.P(tile.Min.X+24, tile.Min.Y+tileHeight/2+(i*2-1)*fontSize*3/4+fontSize/3)
+			d.drawString(img, text, f, fontSize, dot, line)
+		}
+	}
+	return img, nil
+}
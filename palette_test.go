@@ -0,0 +1,72 @@
+package text2img
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadPalette(t *testing.T) {
+	dir, err := ioutil.TempDir("", "palette")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	white, dark := color.RGBA{255, 255, 255, 255}, color.RGBA{0x33, 0x33, 0x33, 255}
+	expected := []Color{
+		{color.RGBA{0x1e, 0x1f, 0x26, 255}, color.RGBA{0xf4, 0xcc, 0x70, 255}},
+		{color.RGBA{0x28, 0x36, 0x55, 255}, white},
+		{color.RGBA{0xfa, 0xf0, 0xe6, 255}, dark},
+	}
+	files := map[string]string{
+		"palette.json": `[
+			{"background": "#1e1f26", "text": "#f4cc70"},
+			{"background": "#283655"},
+			{"background": "#faf0e6"}
+		]`,
+		"palette.csv": "background,text\n#1e1f26,#f4cc70\n#283655\n#faf0e6,\n",
+		"palette.gpl": "GIMP Palette\nName: Course\nColumns: 3\n# comment\n 30  31  38\tNight\n 40  54  85\tNavy\n250 240 230\tLinen\n",
+	}
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		palette, err := FindPalette(path)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		want := expected
+		if name == "palette.gpl" {
+			// GIMP palettes have no text colors
+			want = append([]Color{{expected[0].BackgroundColor, white}}, expected[1:]...)
+		}
+		if !reflect.DeepEqual(palette, want) {
+			t.Errorf("%s: expected %v, got %v", name, want, palette)
+		}
+	}
+
+	if palette, err := FindPalette("default"); err != nil || len(palette) != len(colors) {
+		t.Errorf("the built-in colors must be the default palette")
+	}
+	if _, err := LoadPalette(filepath.Join(dir, "palette.txt")); err == nil {
+		t.Errorf("unknown palette formats must be rejected")
+	}
+}
+
+func TestDrawPalette(t *testing.T) {
+	img, err := DrawPalette(colors[:5])
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	if size := img.Bounds().Size(); size.X != 1200 || size.Y != 320 {
+		t.Errorf("5 colors must be drawn on 2 rows of 4 tiles, got %v", size)
+	}
+	if c := img.At(150, 20); c != colors[0].BackgroundColor {
+		t.Errorf("first tile must have the first background, got %v", c)
+	}
+}
@@ -0,0 +1,170 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// BackgroundKind is the kind of a procedural background
+type BackgroundKind string
+
+// Procedural backgrounds
+const (
+	// BackgroundSolid fills the image with the background color
+	BackgroundSolid BackgroundKind = ""
+	// BackgroundLinear is a gradient through its colors along its angle
+	BackgroundLinear BackgroundKind = "linear"
+	// BackgroundRadial is a gradient through its colors from the center of the image to its corners
+	BackgroundRadial BackgroundKind = "radial"
+	// BackgroundDots, BackgroundStripes and BackgroundGrid draw their first color over the background color,
+	// their shapes being Scale pixels apart
+	BackgroundDots    BackgroundKind = "dots"
+	BackgroundStripes BackgroundKind = "stripes"
+	BackgroundGrid    BackgroundKind = "grid"
+	// BackgroundNoise sprinkles the background color with grain of its first color
+	BackgroundNoise BackgroundKind = "noise"
+)
+
+// Background is a background rendered procedurally instead of a flat color
+type Background struct {
+	Kind BackgroundKind
+	// Colors are the evenly spaced stops of gradients, or the color of patterns.
+	// Patterns without a color use a lighter or darker shade of the background color.
+	Colors []color.RGBA
+	// Angle is the direction of linear gradients and stripes in degrees, 0 going from left to right
+	Angle float64
+	// Scale is the distance between the shapes of patterns in pixels, 40 by default
+	Scale float64
+}
+
+// draw renders the background over img, filled with the base color
+func (b *Background) draw(img *image.RGBA, base color.RGBA) {
+	bounds := img.Bounds()
+	width, height := float64(bounds.Dx()), float64(bounds.Dy())
+	scale := b.Scale
+	if scale <= 0 {
+		scale = 40
+	}
+	ink := b.patternColor(base)
+	sin, cos := math.Sincos(b.Angle * math.Pi / 180)
+	// Length of the projection of the image on the direction of the angle, so linear gradients span the whole image
+	span := math.Abs(width*cos) + math.Abs(height*sin)
+	radius := math.Hypot(width, height) / 2
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			// Pixel center relative to the center of the image
+			px, py := float64(x-bounds.Min.X)+0.5-width/2, float64(y-bounds.Min.Y)+0.5-height/2
+			switch b.Kind {
+			case BackgroundLinear:
+				img.SetRGBA(x, y, gradientAt(b.Colors, base, (px*cos+py*sin)/span+0.5))
+			case BackgroundRadial:
+				img.SetRGBA(x, y, gradientAt(b.Colors, base, math.Hypot(px, py)/radius))
+			case BackgroundDots:
+				dx, dy := math.Mod(px+width, scale)-scale/2, math.Mod(py+height, scale)-scale/2
+				coverage := clamp(scale/6 - math.Hypot(dx, dy) + 0.5)
+				img.SetRGBA(x, y, mix(base, ink, coverage))
+			case BackgroundStripes:
+				position := math.Mod(px*cos+py*sin+span, scale)
+				// Antialias both edges of the stripe
+				coverage := clamp(math.Min(position+0.5, scale/2-position+0.5))
+				img.SetRGBA(x, y, mix(base, ink, coverage))
+			case BackgroundGrid:
+				dx, dy := math.Mod(px+width+1, scale), math.Mod(py+height+1, scale)
+				coverage := clamp(2 - math.Min(dx, dy))
+				img.SetRGBA(x, y, mix(base, ink, coverage))
+			case BackgroundNoise:
+				img.SetRGBA(x, y, mix(base, ink, 0.25*noise(x, y)))
+			}
+		}
+	}
+}
+
+// patternedBackground returns the procedural background of the current colors rendered over base.
+// Backgrounds are rendered once per size and colors and cached, as every frame of a snippet is drawn on the same one.
+func (d *drawer) patternedBackground(base color.RGBA) *image.RGBA {
+	key := patternKey{d.background, base, d.Width, d.Height}
+	if img, ok := d.patterns[key]; ok {
+		return img
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, d.Width, d.Height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(base), image.ZP, draw.Src)
+	d.background.draw(img, base)
+
+	if d.patterns == nil {
+		d.patterns = make(map[patternKey]*image.RGBA)
+	}
+	d.patterns[key] = img
+	return img
+}
+
+// patternKey identifies a procedural background rendered over a base color on a canvas
+type patternKey struct {
+	background *Background
+	base       color.RGBA
+	width      int
+	height     int
+}
+
+// patternColor returns the first color of the background, or a shade of base contrasting slightly with it
+func (b *Background) patternColor(base color.RGBA) color.RGBA {
+	if len(b.Colors) > 0 {
+		return b.Colors[0]
+	}
+	if relativeLuminance(base) > 0.18 {
+		return mix(base, color.RGBA{0, 0, 0, 255}, 0.12)
+	}
+	return mix(base, color.RGBA{255, 255, 255, 255}, 0.12)
+}
+
+// average returns the average of the colors of a gradient, used as its background color
+func (b *Background) average(base color.RGBA) color.RGBA {
+	if len(b.Colors) == 0 || (b.Kind != BackgroundLinear && b.Kind != BackgroundRadial) {
+		return base
+	}
+	var r, g, bl int
+	for _, c := range b.Colors {
+		r, g, bl = r+int(c.R), g+int(c.G), bl+int(c.B)
+	}
+	n := len(b.Colors)
+	return color.RGBA{uint8(r / n), uint8(g / n), uint8(bl / n), 255}
+}
+
+// gradientAt returns the color at t, between 0 and 1, of a gradient through evenly spaced stops
+func gradientAt(stops []color.RGBA, base color.RGBA, t float64) color.RGBA {
+	if len(stops) == 0 {
+		return base
+	}
+	if len(stops) == 1 {
+		return stops[0]
+	}
+	t = clamp(t) * float64(len(stops)-1)
+	i := int(t)
+	if i >= len(stops)-1 {
+		return stops[len(stops)-1]
+	}
+	return mix(stops[i], stops[i+1], t-float64(i))
+}
+
+// mix returns the color at t, between 0 and 1, from c1 to c2
+func mix(c1, c2 color.RGBA, t float64) color.RGBA {
+	lerp := func(a, b uint8) uint8 {
+		return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+	}
+	return color.RGBA{lerp(c1.R, c2.R), lerp(c1.G, c2.G), lerp(c1.B, c2.B), lerp(c1.A, c2.A)}
+}
+
+func clamp(t float64) float64 {
+	return math.Max(0, math.Min(1, t))
+}
+
+// noise returns a pseudo random value between 0 and 1 for a pixel, the same on every run
+func noise(x, y int) float64 {
+	h := uint32(x)*374761393 + uint32(y)*668265263
+	h = (h ^ h>>13) * 1274126177
+	h ^= h >> 16
+	return float64(h&0xffff) / 0xffff
+}
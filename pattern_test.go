@@ -0,0 +1,106 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestGradientAt(t *testing.T) {
+	black, white := color.RGBA{0, 0, 0, 255}, color.RGBA{255, 255, 255, 255}
+	red := color.RGBA{255, 0, 0, 255}
+	stops := []color.RGBA{black, white, red}
+	for _, c := range []struct {
+		t        float64
+		expected color.RGBA
+	}{
+		{-1, black},
+		{0, black},
+		{0.25, color.RGBA{128, 128, 128, 255}},
+		{0.5, white},
+		{0.75, color.RGBA{255, 128, 128, 255}},
+		{1, red},
+		{2, red},
+	} {
+		if got := gradientAt(stops, red, c.t); got != c.expected {
+			t.Errorf("gradientAt(%v) = %v, expected %v", c.t, got, c.expected)
+		}
+	}
+	if got := gradientAt(nil, red, 0.5); got != red {
+		t.Errorf("gradients without stops must be the base color, got %v", got)
+	}
+}
+
+func TestPatternBackgrounds(t *testing.T) {
+	base := color.RGBA{0x28, 0x36, 0x55, 255}
+	white := color.RGBA{255, 255, 255, 255}
+
+	img := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	(&Background{Kind: BackgroundLinear, Colors: []color.RGBA{base, white}}).draw(img, base)
+	if left, right := img.RGBAAt(0, 50), img.RGBAAt(199, 50); left.R > base.R+2 || right.R < 253 {
+		t.Errorf("linear gradient must go from left to right, got %v and %v", left, right)
+	}
+	(&Background{Kind: BackgroundLinear, Colors: []color.RGBA{base, white}, Angle: 90}).draw(img, base)
+	if top, bottom := img.RGBAAt(100, 0), img.RGBAAt(100, 99); top.R >= bottom.R {
+		t.Errorf("gradient at 90 degrees must go from top to bottom, got %v and %v", top, bottom)
+	}
+
+	(&Background{Kind: BackgroundRadial, Colors: []color.RGBA{white, base}}).draw(img, base)
+	if center, corner := img.RGBAAt(100, 50), img.RGBAAt(0, 0); center.R < 250 || corner.R > base.R+2 {
+		t.Errorf("radial gradient must go from the center to the corners, got %v and %v", center, corner)
+	}
+
+	(&Background{Kind: BackgroundDots, Colors: []color.RGBA{white}, Scale: 20}).draw(img, base)
+	if dot, gap := img.RGBAAt(90, 40), img.RGBAAt(100, 50); dot != white || gap != base {
+		t.Errorf("dots must be drawn every 20 pixels, got %v in a dot and %v between dots", dot, gap)
+	}
+
+	(&Background{Kind: BackgroundNoise}).draw(img, base)
+	first := image.NewRGBA(img.Bounds())
+	copy(first.Pix, img.Pix)
+	(&Background{Kind: BackgroundNoise}).draw(img, base)
+	if string(first.Pix) != string(img.Pix) {
+		t.Error("noise must be the same on every run")
+	}
+	if img.RGBAAt(10, 10) == img.RGBAAt(11, 10) && img.RGBAAt(11, 10) == img.RGBAAt(12, 10) {
+		t.Error("noise must vary from pixel to pixel")
+	}
+}
+
+func TestPaletteGradient(t *testing.T) {
+	palette, backgrounds, err := parseJSONPalette([]byte(`[{"kind": "linear", "colors": ["#000", "#fff"], "angle": 45}, {"background": "#fff"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, background := palette[0], backgrounds[0]
+	if background == nil || background.Kind != BackgroundLinear || background.Angle != 45 || len(background.Colors) != 2 {
+		t.Fatalf("palette entry must have a linear gradient, got %+v", background)
+	}
+	if c.BackgroundColor != (color.RGBA{127, 127, 127, 255}) {
+		t.Errorf("background color must be the average of the gradient, got %v", c.BackgroundColor)
+	}
+	if len(backgrounds) != 2 || backgrounds[1] != nil {
+		t.Errorf("solid palette entries must not have a background, got %v", backgrounds)
+	}
+
+	d := &drawer{}
+	d.SetSize(60, 40)
+	d.SetPalette(palette, nil)
+	d.SetPaletteBackgrounds(backgrounds)
+	d.setPaletteColor(0)
+	first := d.drawBackgroundImage()
+	if d.background != background || first.RGBAAt(0, 0) == first.RGBAAt(59, 39) {
+		t.Errorf("the gradient of a palette entry must be drawn with its colors")
+	}
+	if second := d.drawBackgroundImage(); string(first.Pix) != string(second.Pix) || len(d.patterns) != 1 {
+		t.Errorf("gradients must be rendered once and reused, got %d renders", len(d.patterns))
+	}
+	d.setPaletteColor(1)
+	if d.background != nil {
+		t.Errorf("colors without a gradient must be drawn flat, got %+v", d.background)
+	}
+
+	if _, _, err := parseJSONPalette([]byte(`[{"kind": "dots", "colors": ["nope"]}]`)); err == nil {
+		t.Error("invalid pattern colors must be an error")
+	}
+}
@@ -0,0 +1,44 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// roundedRect is an antialiased mask of a rectangle with rounded corners
+type roundedRect struct {
+	rect   image.Rectangle
+	radius float64
+}
+
+func (r roundedRect) ColorModel() color.Model {
+	return color.AlphaModel
+}
+
+func (r roundedRect) Bounds() image.Rectangle {
+	return r.rect
+}
+
+func (r roundedRect) At(x, y int) color.Color {
+	if !image.Pt(x, y).In(r.rect) {
+		return color.Transparent
+	}
+	radius := math.Min(r.radius, math.Min(float64(r.rect.Dx()), float64(r.rect.Dy()))/2)
+	// Distance from the pixel center to the nearest corner circle center, if it lies in a corner
+	px, py := float64(x)+0.5, float64(y)+0.5
+	cx := math.Max(float64(r.rect.Min.X)+radius, math.Min(px, float64(r.rect.Max.X)-radius))
+	cy := math.Max(float64(r.rect.Min.Y)+radius, math.Min(py, float64(r.rect.Max.Y)-radius))
+	distance := math.Hypot(px-cx, py-cy)
+	alpha := math.Max(0, math.Min(1, radius-distance+0.5))
+	if distance == 0 {
+		alpha = 1
+	}
+	return color.Alpha{uint8(alpha * 255)}
+}
+
+// drawRoundedRect fills rect with src, rounding its corners by radius
+func drawRoundedRect(dst draw.Image, rect image.Rectangle, radius float64, src image.Image) {
+	draw.DrawMask(dst, rect, src, image.ZP, roundedRect{rect, radius}, rect.Min, draw.Over)
+}
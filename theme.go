@@ -0,0 +1,320 @@
+package text2img
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gobolditalic"
+	"golang.org/x/image/font/gofont/goitalic"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/font/gofont/goregular"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Theme bundles the fonts, colors and layout shared by a set of images.
+// Themes are either built in, see Themes, or loaded from a JSON or YAML file by LoadTheme.
+// Settings given in Params take precedence over the ones of the theme.
+type Theme struct {
+	Name  string     `json:"name" yaml:"name"`
+	Fonts ThemeFonts `json:"fonts" yaml:"fonts"`
+	// Palette holds the pairs of colors images are drawn with, picked at random.
+	// Entries without a text color get white or dark text, whichever is more readable.
+	Palette []ThemeColor `json:"palette" yaml:"palette"`
+	// AccentColors are the colors of highlights and revealed answers, the one farthest from the background is used
+	AccentColors []string `json:"accentColors" yaml:"accentColors"`
+
+	Margins          Margins       `json:"margins" yaml:"margins"`
+	SafeArea         string        `json:"safeArea" yaml:"safeArea"`
+	LineHeight       float64       `json:"lineHeight" yaml:"lineHeight"`
+	ParagraphSpacing float64       `json:"paragraphSpacing" yaml:"paragraphSpacing"`
+	Align            Align         `json:"align" yaml:"align"`
+	VerticalAlign    VerticalAlign `json:"verticalAlign" yaml:"verticalAlign"`
+
+	Code      ThemeCode      `json:"code" yaml:"code"`
+	Overlay   ThemeOverlay   `json:"overlay" yaml:"overlay"`
+	TextStyle ThemeTextStyle `json:"textStyle" yaml:"textStyle"`
+}
+
+// ThemeFonts are the paths of the fonts of each role, or the names of built-in fonts, see BuiltinFonts
+type ThemeFonts struct {
+	Regular    string   `json:"regular" yaml:"regular"`
+	Bold       string   `json:"bold" yaml:"bold"`
+	Italic     string   `json:"italic" yaml:"italic"`
+	BoldItalic string   `json:"boldItalic" yaml:"boldItalic"`
+	Code       string   `json:"code" yaml:"code"`
+	Fallback   []string `json:"fallback" yaml:"fallback"`
+	Emoji      string   `json:"emoji" yaml:"emoji"`
+}
+
+// ThemeColor is a pair of hex colors, like "#1e1f26", optionally drawn with a gradient or a pattern, see Background.
+// The background of gradients defaults to the average of their colors.
+type ThemeColor struct {
+	Background string         `json:"background" yaml:"background"`
+	Text       string         `json:"text" yaml:"text"`
+	Kind       BackgroundKind `json:"kind" yaml:"kind"`
+	Colors     []string       `json:"colors" yaml:"colors"`
+	Angle      float64        `json:"angle" yaml:"angle"`
+	Scale      float64        `json:"scale" yaml:"scale"`
+}
+
+// ThemeCode is the look of the panel code snippets are drawn on, see CodePanel
+type ThemeCode struct {
+	Background string  `json:"background" yaml:"background"`
+	Text       string  `json:"text" yaml:"text"`
+	Padding    float64 `json:"padding" yaml:"padding"`
+	Radius     float64 `json:"radius" yaml:"radius"`
+}
+
+// ThemeOverlay is the overlay drawn over background images, see Overlay
+type ThemeOverlay struct {
+	Kind    OverlayKind `json:"kind" yaml:"kind"`
+	Color   string      `json:"color" yaml:"color"`
+	Opacity float64     `json:"opacity" yaml:"opacity"`
+	Radius  float64     `json:"radius" yaml:"radius"`
+}
+
+// ThemeTextStyle is the outline and the shadow drawn under text, see TextStyle
+type ThemeTextStyle struct {
+	StrokeWidth   float64 `json:"strokeWidth" yaml:"strokeWidth"`
+	StrokeColor   string  `json:"strokeColor" yaml:"strokeColor"`
+	ShadowX       int     `json:"shadowX" yaml:"shadowX"`
+	ShadowY       int     `json:"shadowY" yaml:"shadowY"`
+	ShadowBlur    float64 `json:"shadowBlur" yaml:"shadowBlur"`
+	ShadowColor   string  `json:"shadowColor" yaml:"shadowColor"`
+	ShadowOpacity float64 `json:"shadowOpacity" yaml:"shadowOpacity"`
+}
+
+// BuiltinFonts are the Go fonts, usable by name wherever a font path is expected
+var BuiltinFonts = map[string][]byte{
+	"go-regular":     goregular.TTF,
+	"go-bold":        gobold.TTF,
+	"go-italic":      goitalic.TTF,
+	"go-bold-italic": gobolditalic.TTF,
+	"go-mono":        gomono.TTF,
+}
+
+// Themes are the built-in themes, selected by name
+var Themes = map[string]Theme{
+	"midnight": {
+		Name: "midnight",
+		Fonts: ThemeFonts{
+			Regular: "go-regular", Bold: "go-bold", Italic: "go-italic", BoldItalic: "go-bold-italic", Code: "go-mono",
+		},
+		Palette: []ThemeColor{
+			{Background: "#1e1f26", Text: "#fff"},
+			{Background: "#283655", Text: "#fff"},
+			{Background: "#04202c", Text: "#fff"},
+			{Background: "#304040", Text: "#fff"},
+			{Background: "#003d47", Text: "#fff"},
+			{Text: "#fff", Kind: BackgroundLinear, Colors: []string{"#1e1f26", "#283655"}, Angle: 135},
+			{Background: "#1e1f26", Text: "#fff", Kind: BackgroundGrid, Scale: 48},
+		},
+		AccentColors: []string{"#f4cc70", "#de7a22", "#20948b"},
+		Margins:      Margins{Top: 48, Right: 64, Bottom: 48, Left: 64},
+		Code:         ThemeCode{Background: "#0b0c10", Text: "#c5c6c7", Padding: 0.6, Radius: 0.3},
+		Overlay:      ThemeOverlay{Kind: OverlayDim, Opacity: 0.55},
+	},
+	"paper": {
+		Name: "paper",
+		Fonts: ThemeFonts{
+			Regular: "go-regular", Bold: "go-bold", Italic: "go-italic", BoldItalic: "go-bold-italic", Code: "go-mono",
+		},
+		Palette: []ThemeColor{
+			{Background: "#f8f5ec", Text: "#333"},
+			{Background: "#eeeeee", Text: "#333"},
+			{Background: "#f1f1f2", Text: "#283655"},
+			{Background: "#fff5e1", Text: "#4f4a45"},
+			{Background: "#f8f5ec", Text: "#333", Kind: BackgroundDots, Scale: 32},
+			{Background: "#f8f5ec", Text: "#333", Kind: BackgroundNoise},
+		},
+		AccentColors:  []string{"#ffd95a", "#a1d6e2"},
+		Margins:       Margins{Top: 64, Right: 96, Bottom: 64, Left: 96},
+		LineHeight:    1.6,
+		Align:         AlignLeft,
+		VerticalAlign: VerticalAlignMiddle,
+		Code:          ThemeCode{Background: "#e8e4d8", Text: "#283655", Padding: 0.6, Radius: 0.2},
+		Overlay:       ThemeOverlay{Kind: OverlayBox, Color: "#fff", Opacity: 0.8},
+	},
+	"shorts": {
+		Name: "shorts",
+		Fonts: ThemeFonts{
+			Regular: "go-bold", Bold: "go-bold", Italic: "go-bold-italic", BoldItalic: "go-bold-italic", Code: "go-mono",
+		},
+		Palette: []ThemeColor{
+			{Background: "#d24136", Text: "#fff"},
+			{Background: "#128277", Text: "#fff"},
+			{Background: "#283655", Text: "#fff"},
+			{Text: "#fff", Kind: BackgroundRadial, Colors: []string{"#d24136", "#872b76"}},
+			{Text: "#fff", Kind: BackgroundLinear, Colors: []string{"#128277", "#283655"}, Angle: 90},
+		},
+		SafeArea:   "youtube-shorts",
+		Margins:    Margins{Top: 40, Right: 40, Bottom: 40, Left: 40},
+		LineHeight: 1.3,
+		Code:       ThemeCode{Background: "#1e1f26", Text: "#fff", Padding: 0.5, Radius: 0.3},
+		Overlay:    ThemeOverlay{Kind: OverlayScrim, Opacity: 0.6},
+		TextStyle:  ThemeTextStyle{ShadowY: 4, ShadowBlur: 12, ShadowOpacity: 0.6},
+	},
+}
+
+// FindTheme returns the built-in theme of that name, or loads the theme file at that path
+func FindTheme(nameOrPath string) (Theme, error) {
+	if theme, ok := Themes[nameOrPath]; ok {
+		return theme, nil
+	}
+	return LoadTheme(nameOrPath)
+}
+
+// LoadTheme loads a theme from a JSON or YAML file. Font paths are relative to the directory of the file.
+func LoadTheme(path string) (theme Theme, err error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(src, &theme)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(src, &theme)
+	default:
+		err = fmt.Errorf("theme %s must be a .json, .yaml or .yml file", path)
+	}
+	if err != nil {
+		return
+	}
+	if theme.Name == "" {
+		theme.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+
+	dir := filepath.Dir(path)
+	resolve := func(fontPath string) string {
+		if _, ok := BuiltinFonts[fontPath]; ok || fontPath == "" || filepath.IsAbs(fontPath) {
+			return fontPath
+		}
+		return filepath.Join(dir, fontPath)
+	}
+	fonts := &theme.Fonts
+	fonts.Regular, fonts.Bold, fonts.Italic = resolve(fonts.Regular), resolve(fonts.Bold), resolve(fonts.Italic)
+	fonts.BoldItalic, fonts.Code, fonts.Emoji = resolve(fonts.BoldItalic), resolve(fonts.Code), resolve(fonts.Emoji)
+	for i := range fonts.Fallback {
+		fonts.Fallback[i] = resolve(fonts.Fallback[i])
+	}
+	return
+}
+
+// fill returns params with the settings it leaves unset taken from the theme
+func (t Theme) fill(params Params) (Params, error) {
+	setString := func(value *string, themeValue string) {
+		if *value == "" {
+			*value = themeValue
+		}
+	}
+	setString(&params.FontPath, t.Fonts.Regular)
+	setString(&params.BoldFontPath, t.Fonts.Bold)
+	setString(&params.ItalicFontPath, t.Fonts.Italic)
+	setString(&params.BoldItalicFontPath, t.Fonts.BoldItalic)
+	setString(&params.CodeFontPath, t.Fonts.Code)
+	setString(&params.EmojiDir, t.Fonts.Emoji)
+	setString(&params.SafeArea, t.SafeArea)
+	if len(params.FallbackFontPaths) == 0 {
+		params.FallbackFontPaths = t.Fonts.Fallback
+	}
+
+	hex := func(what, value string) (c color.RGBA, err error) {
+		if c, err = Hex(value); err != nil {
+			err = fmt.Errorf("theme %s: %s %q: %s", t.Name, what, value, err.Error())
+		}
+		return
+	}
+	if len(params.Palette) == 0 {
+		for _, c := range t.Palette {
+			pair, background, err := c.color()
+			if err != nil {
+				return params, fmt.Errorf("theme %s: %s", t.Name, err.Error())
+			}
+			params.Palette = append(params.Palette, pair)
+			params.PaletteBackgrounds = append(params.PaletteBackgrounds, background)
+		}
+	}
+	if len(params.AccentColors) == 0 {
+		for _, value := range t.AccentColors {
+			accent, err := hex("accent color", value)
+			if err != nil {
+				return params, err
+			}
+			params.AccentColors = append(params.AccentColors, accent)
+		}
+	}
+	if params.CodePanel == (CodePanel{}) {
+		params.CodePanel = CodePanel{Padding: t.Code.Padding, Radius: t.Code.Radius}
+		var err error
+		if t.Code.Background != "" {
+			if params.CodePanel.Background, err = hex("code background", t.Code.Background); err != nil {
+				return params, err
+			}
+		}
+		if t.Code.Text != "" {
+			if params.CodePanel.TextColor, err = hex("code text color", t.Code.Text); err != nil {
+				return params, err
+			}
+		}
+	}
+
+	if params.Overlay == (Overlay{}) {
+		params.Overlay = Overlay{Kind: t.Overlay.Kind, Opacity: t.Overlay.Opacity, Radius: t.Overlay.Radius}
+		if t.Overlay.Color != "" {
+			var err error
+			if params.Overlay.Color, err = hex("overlay color", t.Overlay.Color); err != nil {
+				return params, err
+			}
+		}
+	}
+	// The text style is merged field by field, so that an outline set in params keeps the shadow of the theme
+	style := &params.TextStyle
+	if style.StrokeWidth == 0 {
+		style.StrokeWidth = t.TextStyle.StrokeWidth
+	}
+	if style.StrokeColor == (color.RGBA{}) && t.TextStyle.StrokeColor != "" {
+		var err error
+		if style.StrokeColor, err = hex("stroke color", t.TextStyle.StrokeColor); err != nil {
+			return params, err
+		}
+	}
+	if style.ShadowOffset == image.ZP {
+		style.ShadowOffset = image.Pt(t.TextStyle.ShadowX, t.TextStyle.ShadowY)
+	}
+	if style.ShadowBlur == 0 {
+		style.ShadowBlur = t.TextStyle.ShadowBlur
+	}
+	if style.ShadowColor == (color.RGBA{}) && t.TextStyle.ShadowColor != "" {
+		var err error
+		if style.ShadowColor, err = hex("shadow color", t.TextStyle.ShadowColor); err != nil {
+			return params, err
+		}
+	}
+	if style.ShadowOpacity == 0 {
+		style.ShadowOpacity = t.TextStyle.ShadowOpacity
+	}
+
+	if params.Margins == (Margins{}) {
+		params.Margins = t.Margins
+	}
+	if params.LineHeight == 0 {
+		params.LineHeight = t.LineHeight
+	}
+	if params.ParagraphSpacing == 0 {
+		params.ParagraphSpacing = t.ParagraphSpacing
+	}
+	if params.Align == "" {
+		params.Align = t.Align
+	}
+	if params.VerticalAlign == "" {
+		params.VerticalAlign = t.VerticalAlign
+	}
+	return params, nil
+}
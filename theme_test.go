@@ -0,0 +1,113 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testThemeYAML = `
+fonts:
+  regular: fonts/regular.ttf
+  code: go-mono
+palette:
+  - background: "#1e1f26"
+    text: "#fff"
+accentColors: ["#f4cc70"]
+margins:
+  top: 10
+  left: 20
+lineHeight: 1.2
+align: left
+code:
+  background: "#000"
+  padding: 0.5
+`
+
+const testThemeJSON = `{
+	"fonts": {"regular": "fonts/regular.ttf", "code": "go-mono"},
+	"palette": [{"background": "#1e1f26", "text": "#fff"}],
+	"accentColors": ["#f4cc70"],
+	"margins": {"top": 10, "left": 20},
+	"lineHeight": 1.2,
+	"align": "left",
+	"code": {"background": "#000", "padding": 0.5}
+}`
+
+func TestLoadTheme(t *testing.T) {
+	dir, err := ioutil.TempDir("", "theme")
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	defer os.RemoveAll(dir)
+
+	for name, src := range map[string]string{"course.yaml": testThemeYAML, "course.json": testThemeJSON} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+			t.Fatal(err.Error())
+		}
+		theme, err := FindTheme(path)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		if theme.Name != "course" {
+			t.Errorf("%s: theme must be named after its file, got %q", name, theme.Name)
+		}
+		if theme.Fonts.Regular != filepath.Join(dir, "fonts/regular.ttf") || theme.Fonts.Code != "go-mono" {
+			t.Errorf("%s: font paths must be relative to the theme file, got %+v", name, theme.Fonts)
+		}
+
+		params, err := theme.fill(Params{LineHeight: 2})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		if params.LineHeight != 2 || params.Align != AlignLeft || params.Margins != (Margins{Top: 10, Left: 20}) {
+			t.Errorf("%s: params must keep their settings and take the others from the theme, got %+v", name, params)
+		}
+		expected := []Color{{color.RGBA{0x1e, 0x1f, 0x26, 255}, color.RGBA{255, 255, 255, 255}}}
+		if !reflect.DeepEqual(params.Palette, expected) {
+			t.Errorf("%s: expected palette %v, got %v", name, expected, params.Palette)
+		}
+		if params.CodePanel != (CodePanel{Background: color.RGBA{0, 0, 0, 255}, Padding: 0.5}) {
+			t.Errorf("%s: unexpected code panel %+v", name, params.CodePanel)
+		}
+	}
+
+	if _, err := LoadTheme(filepath.Join(dir, "missing.toml")); err == nil {
+		t.Errorf("missing themes must be rejected")
+	}
+}
+
+func TestBuiltinThemes(t *testing.T) {
+	for name := range Themes {
+		d, err := NewDrawer(Params{Theme: name})
+		if err != nil {
+			t.Fatalf("%s: %s", name, err.Error())
+		}
+		dr := d.(*drawer)
+		if dr.Font == nil || dr.CodeFont == nil || len(dr.Palette) == 0 {
+			t.Errorf("%s: built-in themes must set fonts and a palette", name)
+		}
+		for index, entry := range Themes[name].Palette {
+			dr.setPaletteColor(index)
+			if entry.Kind == BackgroundSolid && dr.background != nil {
+				t.Errorf("%s: palette entry %d must be drawn flat, got %+v", name, index, dr.background)
+			} else if entry.Kind != BackgroundSolid && (dr.background == nil || dr.background.Kind != entry.Kind || len(dr.background.Colors) != len(entry.Colors)) {
+				t.Errorf("%s: palette entry %d must be drawn with its %s background, got %+v", name, index, entry.Kind, dr.background)
+			}
+		}
+	}
+
+	params, err := Themes["shorts"].fill(Params{TextStyle: TextStyle{StrokeWidth: 2}})
+	if err != nil {
+		t.Fatal(err.Error())
+	}
+	expected := TextStyle{StrokeWidth: 2, ShadowOffset: image.Pt(0, 4), ShadowBlur: 12, ShadowOpacity: 0.6}
+	if params.TextStyle != expected {
+		t.Errorf("an outline must keep the shadow of the theme, expected %+v, got %+v", expected, params.TextStyle)
+	}
+}
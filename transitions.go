@@ -0,0 +1,64 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Transition is the effect of the frames generated between consecutive snippets
+type Transition string
+
+// Transitions between snippets
+const (
+	// TransitionNone generates no frame between snippets
+	TransitionNone Transition = ""
+	// TransitionFade cross-fades from one snippet to the next
+	TransitionFade Transition = "fade"
+	// TransitionPush slides the next snippet in from the right, pushing the previous one out
+	TransitionPush Transition = "push"
+	// TransitionWipe uncovers the next snippet from left to right
+	TransitionWipe Transition = "wipe"
+)
+
+// transitionFrameCount returns the number of frames generated between two snippets
+func (d *drawer) transitionFrameCount() int {
+	if d.Transition == TransitionNone {
+		return 0
+	}
+	return int(math.Round(d.TransitionDuration * d.FrameRate))
+}
+
+// drawTransition draws the transition from one image to the next at progress t, between 0 and 1.
+// Missing images, like placeholders that failed to load, are replaced by the other one, or by the background
+// when both are missing.
+func (d *drawer) drawTransition(from, to image.Image, t float64) *image.RGBA {
+	if from == nil && to == nil {
+		return d.drawBackgroundImage()
+	}
+	if from == nil {
+		from = to
+	} else if to == nil {
+		to = from
+	}
+	bounds := to.Bounds()
+	img := image.NewRGBA(bounds)
+	draw.Draw(img, bounds, from, from.Bounds().Min, draw.Src)
+
+	// Ease in and out so that the motion starts and stops smoothly
+	t = t * t * (3 - 2*t)
+	offset := int(math.Round(t * float64(bounds.Dx())))
+
+	switch d.Transition {
+	case TransitionPush:
+		draw.Draw(img, bounds, from, from.Bounds().Min.Add(image.Pt(offset, 0)), draw.Src)
+		draw.Draw(img, image.Rect(bounds.Max.X-offset, bounds.Min.Y, bounds.Max.X, bounds.Max.Y), to, to.Bounds().Min, draw.Src)
+	case TransitionWipe:
+		draw.Draw(img, image.Rect(bounds.Min.X, bounds.Min.Y, bounds.Min.X+offset, bounds.Max.Y), to, to.Bounds().Min, draw.Src)
+	default:
+		mask := image.NewUniform(color.Alpha{uint8(math.Round(t * 255))})
+		draw.DrawMask(img, bounds, to, to.Bounds().Min, mask, image.ZP, draw.Over)
+	}
+	return img
+}
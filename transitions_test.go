@@ -0,0 +1,49 @@
+package text2img
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func uniformImage(c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 10))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	return img
+}
+
+func TestDrawTransition(t *testing.T) {
+	from := uniformImage(color.Black)
+	to := uniformImage(color.White)
+	to.Set(0, 0, color.RGBA{255, 0, 0, 255})
+
+	d := &drawer{}
+	d.SetTransition(TransitionFade, 0)
+	if r, _, _, _ := d.drawTransition(from, to, 0.5).At(50, 5).RGBA(); r>>8 < 120 || r>>8 > 135 {
+		t.Errorf("fade must be half way at 0.5, got %v", r>>8)
+	}
+
+	d.SetTransition(TransitionWipe, 0)
+	img := d.drawTransition(from, to, 0.5)
+	if img.RGBAAt(10, 5) != (color.RGBA{255, 255, 255, 255}) || img.RGBAAt(90, 5) != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("wipe must uncover the left half at 0.5")
+	}
+
+	d.SetTransition(TransitionPush, 0)
+	img = d.drawTransition(from, to, 0.5)
+	if img.RGBAAt(50, 0) != (color.RGBA{255, 0, 0, 255}) || img.RGBAAt(49, 5) != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("push must move the left edge of the next image to the middle at 0.5")
+	}
+	if err := d.SetTransition("dissolve", 0); err == nil || d.Transition != TransitionPush {
+		t.Errorf("unknown transitions must be rejected, got %v", err)
+	}
+	if _, err := NewDrawer(Params{Transition: "dissolve"}); err == nil {
+		t.Error("drawers with an unknown transition must not be created")
+	}
+	d.SetSize(100, 10)
+	d.SetColors(color.RGBA{255, 255, 255, 255}, color.RGBA{18, 52, 86, 255})
+	if img = d.drawTransition(nil, nil, 0.5); img.RGBAAt(50, 5) != (color.RGBA{18, 52, 86, 255}) {
+		t.Errorf("transitions between missing images must show the background, got %v", img.RGBAAt(50, 5))
+	}
+}
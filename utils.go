@@ -24,6 +24,11 @@ func Hex(scol string) (color.RGBA, error) {
 	return color.RGBA{r * factor, g * factor, b * factor, 255}, nil
 }
 
+// HexString returns the hex notation of a color, like "#1e1f26"
+func HexString(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
 func must(c color.RGBA, e error) color.RGBA {
 	return c
 }